@@ -0,0 +1,30 @@
+package config
+
+import (
+	"flag"
+	"os"
+)
+
+type Config struct {
+	StartHost string
+	RulesFile string
+}
+
+func ParseFlags() *Config {
+	startHost := flag.String("a", "0.0.0.0:8081", "address and port to run accrual mock")
+	rulesFile := flag.String("c", "", "path to JSON file with accrual rules")
+
+	flag.Parse()
+
+	if envRunAddr := os.Getenv("ACCRUAL_MOCK_ADDRESS"); envRunAddr != "" {
+		*startHost = envRunAddr
+	}
+	if envRules := os.Getenv("ACCRUAL_MOCK_RULES"); envRules != "" {
+		*rulesFile = envRules
+	}
+
+	return &Config{
+		StartHost: *startHost,
+		RulesFile: *rulesFile,
+	}
+}
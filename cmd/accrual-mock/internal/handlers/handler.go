@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/accrual-mock/internal/rules"
+)
+
+type orderResponse struct {
+	Order   string   `json:"order"`
+	Status  string   `json:"status"`
+	Accrual *float64 `json:"accrual,omitempty"`
+}
+
+type Handler struct {
+	cfg *rules.Config
+
+	mu        sync.Mutex
+	steps     map[string]int
+	served    int
+	throttled int
+}
+
+func NewHandler(cfg *rules.Config) *Handler {
+	return &Handler{cfg: cfg, steps: make(map[string]int)}
+}
+
+func (h *Handler) GetOrder(c *gin.Context) {
+	h.sleepLatency()
+
+	if retryAfter, ok := h.throttle(); ok {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		c.String(http.StatusTooManyRequests, "too many requests")
+		return
+	}
+
+	number := c.Param("number")
+	rule := h.cfg.Find(number)
+	if rule.NotRegistered {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	resp := orderResponse{Order: number, Status: h.nextStatus(number, rule.Sequence)}
+	if resp.Status == rules.StatusProcessed {
+		accrual := rule.AccrualValue()
+		if accrual > 0 {
+			resp.Accrual = &accrual
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Reset сбрасывает прогресс заказов и счётчики ограничения запросов.
+func (h *Handler) Reset(c *gin.Context) {
+	h.mu.Lock()
+	h.steps = make(map[string]int)
+	h.served = 0
+	h.throttled = 0
+	h.mu.Unlock()
+
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) nextStatus(number string, sequence []string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	step := h.steps[number]
+	if step >= len(sequence) {
+		step = len(sequence) - 1
+	}
+	h.steps[number] = step + 1
+	return sequence[step]
+}
+
+func (h *Handler) throttle() (time.Duration, bool) {
+	t := h.cfg.Throttle
+	if t == nil || t.Every <= 0 || t.Length <= 0 {
+		return 0, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.served < t.Every {
+		h.served++
+		return 0, false
+	}
+	h.throttled++
+	if h.throttled >= t.Length {
+		h.served = 0
+		h.throttled = 0
+	}
+	return time.Duration(t.RetryAfter), true
+}
+
+func (h *Handler) sleepLatency() {
+	minD, maxD := time.Duration(h.cfg.Latency.Min), time.Duration(h.cfg.Latency.Max)
+	if maxD <= 0 {
+		return
+	}
+	d := minD
+	if maxD > minD {
+		d += time.Duration(rand.Int63n(int64(maxD - minD)))
+	}
+	time.Sleep(d)
+}
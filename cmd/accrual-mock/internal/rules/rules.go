@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	StatusRegistered = "REGISTERED"
+	StatusInvalid    = "INVALID"
+	StatusProcessing = "PROCESSING"
+	StatusProcessed  = "PROCESSED"
+)
+
+// Duration читается из JSON строкой вида "150ms".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Rule описывает поведение системы начислений для заказов, подходящих под Match или Prefix.
+// Каждый запрос по заказу сдвигает его на шаг по Sequence, последний статус остаётся навсегда.
+// Начисление — Accrual, если задано, иначе Amount * RewardPercent / 100.
+type Rule struct {
+	Match         string   `json:"match,omitempty"`
+	Prefix        string   `json:"prefix,omitempty"`
+	NotRegistered bool     `json:"not_registered,omitempty"`
+	Sequence      []string `json:"sequence,omitempty"`
+	Amount        float64  `json:"amount,omitempty"`
+	RewardPercent float64  `json:"reward_percent,omitempty"`
+	Accrual       *float64 `json:"accrual,omitempty"`
+}
+
+// Throttle отвечает 429 на Length запросов после каждых Every успешных.
+type Throttle struct {
+	Every      int      `json:"every"`
+	Length     int      `json:"length"`
+	RetryAfter Duration `json:"retry_after"`
+}
+
+type Latency struct {
+	Min Duration `json:"min"`
+	Max Duration `json:"max"`
+}
+
+type Config struct {
+	Default  Rule      `json:"default"`
+	Rules    []Rule    `json:"rules"`
+	Throttle *Throttle `json:"throttle,omitempty"`
+	Latency  Latency   `json:"latency"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Default: Rule{
+			Sequence:      []string{StatusRegistered, StatusProcessing, StatusProcessed},
+			Amount:        500,
+			RewardPercent: 10,
+		},
+	}
+}
+
+func Load(path string) (*Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	all := append([]Rule{c.Default}, c.Rules...)
+	for i, r := range all {
+		if r.NotRegistered {
+			continue
+		}
+		if len(r.Sequence) == 0 {
+			return fmt.Errorf("rule %d: empty sequence", i)
+		}
+		for _, st := range r.Sequence {
+			switch st {
+			case StatusRegistered, StatusInvalid, StatusProcessing, StatusProcessed:
+			default:
+				return fmt.Errorf("rule %d: unknown status %q", i, st)
+			}
+		}
+	}
+	if c.Latency.Max < c.Latency.Min {
+		return fmt.Errorf("latency max is less than min")
+	}
+	return nil
+}
+
+// Find возвращает первое подходящее правило: сначала точное совпадение, затем префикс.
+func (c *Config) Find(orderNumber string) Rule {
+	for _, r := range c.Rules {
+		if r.Match != "" && r.Match == orderNumber {
+			return r
+		}
+	}
+	for _, r := range c.Rules {
+		if r.Match == "" && r.Prefix != "" && strings.HasPrefix(orderNumber, r.Prefix) {
+			return r
+		}
+	}
+	return c.Default
+}
+
+func (r Rule) AccrualValue() float64 {
+	if r.Accrual != nil {
+		return *r.Accrual
+	}
+	return r.Amount * r.RewardPercent / 100
+}
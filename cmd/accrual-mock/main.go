@@ -0,0 +1,53 @@
+// cmd/accrual-mock/main.go
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/accrual-mock/config"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/accrual-mock/internal/handlers"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/accrual-mock/internal/rules"
+)
+
+func main() {
+	cfg := config.ParseFlags()
+
+	rulesCfg, err := rules.Load(cfg.RulesFile)
+	if err != nil {
+		log.Fatalf("failed to load rules: %v", err)
+	}
+
+	handler := handlers.NewHandler(rulesCfg)
+
+	r := gin.New()
+	r.Use(gin.Logger(), gin.Recovery())
+	r.GET("/api/orders/:number", handler.GetOrder)
+	r.POST("/mock/reset", handler.Reset)
+
+	server := &http.Server{
+		Addr:    cfg.StartHost,
+		Handler: r,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		log.Printf("starting accrual mock on %s", cfg.StartHost)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("error starting server: %v", err)
+		}
+	}()
+
+	<-stop
+	if err := server.Close(); err != nil {
+		log.Printf("error shutting down server: %v", err)
+	}
+	log.Println("accrual mock stopped")
+}
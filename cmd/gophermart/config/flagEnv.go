@@ -2,27 +2,97 @@ package config
 
 import (
 	"flag"
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const EnvProduction = "production"
+
 type Config struct {
-	StartHost string
-	DBDSN     string
-	SecretKey string
-	Accrual   string
+	Environment          string
+	StartHost            string
+	DBDSN                string
+	SecretKey            string
+	KeysFile             string
+	Keys                 string
+	Accrual              string
+	SweepInterval        time.Duration
+	Workers              int
+	AccrualRPS           float64
+	AccrualTimeout       time.Duration
+	ReconcileInterval    time.Duration
+	PointsExpiryMonths   int
+	ExpiryInterval       time.Duration
+	AuditSealInterval    time.Duration
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	SessionCacheTTL      time.Duration
+	AuthLimiterStore     string
+	PasswordPolicy       string
+	BcryptCost           int
+	ResetNotifier        string
+	WithdrawOTPThreshold string
+	AdminLogins          []string
+	Args                 []string
 }
 
 func ParseFlags() *Config {
+	environment := flag.String("env", "development", "runtime environment, \"production\" enables strict checks")
 	startHost := flag.String("a", "0.0.0.0:8080", "address and port to run server")
 	accrual := flag.String("r", "0.0.0.0:8080", "address to run accrual")
 	dbDSN := flag.String("d", "", "database DSN for PostgreSQL")
-	secretKey := os.Getenv("SECRET_KEY")
-	if secretKey == "" {
-		secretKey = "verysecretkey"
-	}
+	sweepInterval := flag.Duration("sweep-interval", time.Minute, "interval between pending orders sweeps")
+	reconcileInterval := flag.Duration("reconcile-interval", time.Hour, "interval between balance reconciliations against the ledger")
+	pointsExpiryMonths := flag.Int("points-expiry-months", 0, "accrued points expire this many months after crediting, 0 disables")
+	expiryInterval := flag.Duration("expiry-interval", time.Hour, "interval between runs of the points expiry job")
+	auditSealInterval := flag.Duration("audit-seal-interval", time.Second, "interval between runs of the audit hash chain sealer")
+	workers := flag.Int("w", 4, "number of accrual polling workers")
+	accrualTimeout := flag.Duration("accrual-timeout", 5*time.Second, "timeout of a single request to accrual system")
+	accrualRPS := flag.Float64("accrual-rps", 10, "max requests per second to accrual system, 0 for unlimited")
+	accessTTL := flag.Duration("access-ttl", 15*time.Minute, "lifetime of access tokens")
+	refreshTTL := flag.Duration("refresh-ttl", 30*24*time.Hour, "lifetime of refresh tokens")
+	sessionCacheTTL := flag.Duration("session-cache-ttl", 10*time.Second, "how long a checked session is trusted without a database lookup")
+	authLimiterStore := flag.String("auth-limiter-store", "memory", "where failed login attempts are counted: memory or postgres")
+	passwordPolicy := flag.String("password-policy", "min=8", "password rules: min=N,upper,lower,digit,symbol")
+	bcryptCost := flag.Int("bcrypt-cost", 10, "bcrypt cost of password hashes; raising it rehashes passwords on login")
+	resetNotifier := flag.String("reset-notifier", "log", "password reset token delivery: log or file:<path>")
+	withdrawOTPThreshold := flag.String("withdraw-2fa-threshold", "0", "withdrawals above this amount require a two-factor code, 0 disables")
+	adminLogins := flag.String("admin-logins", "", "comma-separated logins of registered users granted the admin role at startup")
+	keysFile := flag.String("keys-file", "", "JSON file with token signing keys")
 
 	flag.Parse()
 
+	if envEnvironment := os.Getenv("APP_ENV"); envEnvironment != "" {
+		*environment = envEnvironment
+	}
+	if envKeysFile := os.Getenv("AUTH_KEYS_FILE"); envKeysFile != "" {
+		*keysFile = envKeysFile
+	}
+	if envLimiterStore := os.Getenv("AUTH_LIMITER_STORE"); envLimiterStore != "" {
+		*authLimiterStore = envLimiterStore
+	}
+	if envPolicy := os.Getenv("PASSWORD_POLICY"); envPolicy != "" {
+		*passwordPolicy = envPolicy
+	}
+	if envCost := os.Getenv("BCRYPT_COST"); envCost != "" {
+		n, err := strconv.Atoi(envCost)
+		if err != nil {
+			log.Fatalf("invalid BCRYPT_COST: %v", err)
+		}
+		*bcryptCost = n
+	}
+	if envNotifier := os.Getenv("PASSWORD_RESET_NOTIFIER"); envNotifier != "" {
+		*resetNotifier = envNotifier
+	}
+	if envOTPLimit := os.Getenv("WITHDRAW_2FA_THRESHOLD"); envOTPLimit != "" {
+		*withdrawOTPThreshold = envOTPLimit
+	}
+	if envAdmins := os.Getenv("ADMIN_LOGINS"); envAdmins != "" {
+		*adminLogins = envAdmins
+	}
 	if envRunAddr := os.Getenv("RUN_ADDRESS"); envRunAddr != "" {
 		*startHost = envRunAddr
 	}
@@ -32,11 +102,123 @@ func ParseFlags() *Config {
 	if envDB := os.Getenv("DATABASE_URI"); envDB != "" {
 		*dbDSN = envDB
 	}
+	if envSweep := os.Getenv("ORDER_SWEEP_INTERVAL"); envSweep != "" {
+		d, err := time.ParseDuration(envSweep)
+		if err != nil {
+			log.Fatalf("invalid ORDER_SWEEP_INTERVAL: %v", err)
+		}
+		*sweepInterval = d
+	}
+	if envWorkers := os.Getenv("ACCRUAL_WORKERS"); envWorkers != "" {
+		n, err := strconv.Atoi(envWorkers)
+		if err != nil {
+			log.Fatalf("invalid ACCRUAL_WORKERS: %v", err)
+		}
+		*workers = n
+	}
+	if envRPS := os.Getenv("ACCRUAL_RPS"); envRPS != "" {
+		rps, err := strconv.ParseFloat(envRPS, 64)
+		if err != nil {
+			log.Fatalf("invalid ACCRUAL_RPS: %v", err)
+		}
+		*accrualRPS = rps
+	}
+	if envTimeout := os.Getenv("ACCRUAL_TIMEOUT"); envTimeout != "" {
+		d, err := time.ParseDuration(envTimeout)
+		if err != nil {
+			log.Fatalf("invalid ACCRUAL_TIMEOUT: %v", err)
+		}
+		*accrualTimeout = d
+	}
+	if envReconcile := os.Getenv("RECONCILE_INTERVAL"); envReconcile != "" {
+		d, err := time.ParseDuration(envReconcile)
+		if err != nil {
+			log.Fatalf("invalid RECONCILE_INTERVAL: %v", err)
+		}
+		*reconcileInterval = d
+	}
+	if envExpiryMonths := os.Getenv("POINTS_EXPIRY_MONTHS"); envExpiryMonths != "" {
+		n, err := strconv.Atoi(envExpiryMonths)
+		if err != nil {
+			log.Fatalf("invalid POINTS_EXPIRY_MONTHS: %v", err)
+		}
+		*pointsExpiryMonths = n
+	}
+	if envExpiry := os.Getenv("POINTS_EXPIRY_INTERVAL"); envExpiry != "" {
+		d, err := time.ParseDuration(envExpiry)
+		if err != nil {
+			log.Fatalf("invalid POINTS_EXPIRY_INTERVAL: %v", err)
+		}
+		*expiryInterval = d
+	}
+	if envSeal := os.Getenv("AUDIT_SEAL_INTERVAL"); envSeal != "" {
+		d, err := time.ParseDuration(envSeal)
+		if err != nil {
+			log.Fatalf("invalid AUDIT_SEAL_INTERVAL: %v", err)
+		}
+		*auditSealInterval = d
+	}
+	if envAccessTTL := os.Getenv("ACCESS_TOKEN_TTL"); envAccessTTL != "" {
+		d, err := time.ParseDuration(envAccessTTL)
+		if err != nil {
+			log.Fatalf("invalid ACCESS_TOKEN_TTL: %v", err)
+		}
+		*accessTTL = d
+	}
+	if envRefreshTTL := os.Getenv("REFRESH_TOKEN_TTL"); envRefreshTTL != "" {
+		d, err := time.ParseDuration(envRefreshTTL)
+		if err != nil {
+			log.Fatalf("invalid REFRESH_TOKEN_TTL: %v", err)
+		}
+		*refreshTTL = d
+	}
+	if envSessionCache := os.Getenv("SESSION_CACHE_TTL"); envSessionCache != "" {
+		d, err := time.ParseDuration(envSessionCache)
+		if err != nil {
+			log.Fatalf("invalid SESSION_CACHE_TTL: %v", err)
+		}
+		*sessionCacheTTL = d
+	}
 
 	return &Config{
-		StartHost: *startHost,
-		DBDSN:     *dbDSN,
-		Accrual:   *accrual,
-		SecretKey: secretKey,
+		Environment:          *environment,
+		StartHost:            *startHost,
+		DBDSN:                *dbDSN,
+		Accrual:              *accrual,
+		SecretKey:            os.Getenv("SECRET_KEY"),
+		KeysFile:             *keysFile,
+		Keys:                 os.Getenv("AUTH_KEYS"),
+		SweepInterval:        *sweepInterval,
+		Workers:              *workers,
+		AccrualRPS:           *accrualRPS,
+		AccrualTimeout:       *accrualTimeout,
+		ReconcileInterval:    *reconcileInterval,
+		PointsExpiryMonths:   *pointsExpiryMonths,
+		ExpiryInterval:       *expiryInterval,
+		AuditSealInterval:    *auditSealInterval,
+		AccessTokenTTL:       *accessTTL,
+		RefreshTokenTTL:      *refreshTTL,
+		SessionCacheTTL:      *sessionCacheTTL,
+		AuthLimiterStore:     *authLimiterStore,
+		PasswordPolicy:       *passwordPolicy,
+		BcryptCost:           *bcryptCost,
+		ResetNotifier:        *resetNotifier,
+		WithdrawOTPThreshold: *withdrawOTPThreshold,
+		AdminLogins:          splitList(*adminLogins),
+		Args:                 flag.Args(),
+	}
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
 	}
+	return res
+}
+
+func (c *Config) Production() bool {
+	return c.Environment == EnvProduction
 }
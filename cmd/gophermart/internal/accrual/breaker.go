@@ -0,0 +1,70 @@
+package accrual
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker размыкается после threshold подряд неудачных запросов и не пропускает
+// запросы cooldown; затем пропускает один пробный запрос (half-open).
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow возвращает 0, если запрос можно выполнять, иначе время до следующей попытки.
+func (b *CircuitBreaker) Allow() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return 0
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return b.openUntil.Sub(now)
+	}
+	if b.probing {
+		return b.cooldown
+	}
+	b.probing = true
+	return 0
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// Cancel снимает пробный запрос, не меняя счётчик ошибок.
+func (b *CircuitBreaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
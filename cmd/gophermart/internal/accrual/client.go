@@ -0,0 +1,160 @@
+package accrual
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+const defaultRateLimitRetry = 5 * time.Second
+
+type Options struct {
+	RequestTimeout   time.Duration
+	MaxRetries       int
+	BaseBackoff      time.Duration
+	MaxBackoff       time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		RequestTimeout:   5 * time.Second,
+		MaxRetries:       3,
+		BaseBackoff:      200 * time.Millisecond,
+		MaxBackoff:       5 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+type HTTPClient struct {
+	baseURL    string
+	httpClient *http.Client
+	opts       Options
+	breaker    *CircuitBreaker
+}
+
+func NewHTTPClient(baseURL string, opts Options) *HTTPClient {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	return &HTTPClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+		opts:       opts,
+		breaker:    NewCircuitBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+	}
+}
+
+func (c *HTTPClient) GetOrderAccrual(ctx context.Context, orderNumber string) (*models.AccrualResponse, error) {
+	if wait := c.breaker.Allow(); wait > 0 {
+		return nil, &RetryAfterError{Err: ErrCircuitOpen, RetryAfter: wait}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepCtx(ctx, c.backoff(attempt)); err != nil {
+				c.breaker.Cancel()
+				return nil, err
+			}
+		}
+
+		res, retryable, err := c.do(ctx, orderNumber)
+		if err == nil || !retryable {
+			// 204 и 429 — штатные ответы живого сервиса, размыкать из-за них не нужно
+			c.breaker.Success()
+			return res, err
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			// отмена со стороны gophermart не говорит о недоступности сервиса
+			c.breaker.Cancel()
+			return nil, ctx.Err()
+		}
+	}
+
+	c.breaker.Failure()
+	return nil, lastErr
+}
+
+// do выполняет один запрос; retryable означает сетевую ошибку или 5xx.
+func (c *HTTPClient) do(ctx context.Context, orderNumber string) (*models.AccrualResponse, bool, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, c.opts.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, c.baseURL+"/api/orders/"+url.PathEscape(orderNumber), nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var res models.AccrualResponse
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			return nil, true, fmt.Errorf("failed to decode accrual response: %w", err)
+		}
+		return &res, false, nil
+	case resp.StatusCode == http.StatusNoContent:
+		return nil, false, ErrOrderNotRegistered
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, false, &RetryAfterError{Err: ErrRateLimited, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, true, fmt.Errorf("accrual system responded with status %d", resp.StatusCode)
+	default:
+		return nil, false, fmt.Errorf("unexpected accrual system status %d", resp.StatusCode)
+	}
+}
+
+// backoff — экспоненциальная задержка с ограничением сверху и full jitter.
+func (c *HTTPClient) backoff(attempt int) time.Duration {
+	delay := c.opts.BaseBackoff
+	for i := 1; i < attempt && delay < c.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > c.opts.MaxBackoff {
+		delay = c.opts.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+func parseRetryAfter(val string) time.Duration {
+	if sec, err := strconv.Atoi(val); err == nil && sec >= 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return defaultRateLimitRetry
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
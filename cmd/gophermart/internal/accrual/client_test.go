@@ -0,0 +1,187 @@
+package accrual
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+func testOptions() Options {
+	return Options{
+		RequestTimeout:   time.Second,
+		MaxRetries:       2,
+		BaseBackoff:      time.Millisecond,
+		MaxBackoff:       2 * time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  50 * time.Millisecond,
+	}
+}
+
+// newTestServer отвечает handler'ом и считает запросы.
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*HTTPClient, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return NewHTTPClient(srv.URL, testOptions()), &calls
+}
+
+func TestGetOrderAccrualProcessed(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/orders/12345678903" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order":"12345678903","status":"PROCESSED","accrual":729.98}`))
+	})
+
+	res, err := client.GetOrderAccrual(context.Background(), "12345678903")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != models.AccrualStatusProcessed || res.Accrual == nil || res.Accrual.String() != "729.98" {
+		t.Errorf("unexpected response %+v", res)
+	}
+}
+
+func TestGetOrderAccrualNoContent(t *testing.T) {
+	client, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err := client.GetOrderAccrual(context.Background(), "1")
+	if !errors.Is(err, ErrOrderNotRegistered) {
+		t.Fatalf("error = %v, want ErrOrderNotRegistered", err)
+	}
+	if *calls != 1 {
+		t.Errorf("204 must not be retried, got %d requests", *calls)
+	}
+}
+
+func TestGetOrderAccrualRateLimited(t *testing.T) {
+	client, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetOrderAccrual(context.Background(), "1")
+		var retryErr *RetryAfterError
+		if !errors.As(err, &retryErr) || !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("error = %v, want RetryAfterError with ErrRateLimited", err)
+		}
+		if retryErr.RetryAfter != 7*time.Second {
+			t.Errorf("RetryAfter = %v, want 7s", retryErr.RetryAfter)
+		}
+	}
+	// 429 не повторяется и не размыкает предохранитель
+	if *calls != 3 {
+		t.Errorf("got %d requests, want 3", *calls)
+	}
+}
+
+func TestGetOrderAccrualBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	client, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"order":"1","status":"PROCESSING"}`))
+	})
+	ctx := context.Background()
+
+	// каждый вызов — MaxRetries+1 попыток, два неудачных вызова размыкают предохранитель
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetOrderAccrual(ctx, "1"); err == nil {
+			t.Fatal("expected error on 500")
+		}
+	}
+	if *calls != 6 {
+		t.Fatalf("got %d requests, want 6", *calls)
+	}
+
+	_, err := client.GetOrderAccrual(ctx, "1")
+	var retryErr *RetryAfterError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &retryErr) || retryErr.RetryAfter <= 0 {
+		t.Fatalf("error = %v, want ErrCircuitOpen with a delay", err)
+	}
+	if *calls != 6 {
+		t.Fatalf("open breaker must not send requests, got %d", *calls)
+	}
+
+	// неудачная пробная попытка после паузы снова размыкает предохранитель
+	time.Sleep(testOptions().BreakerCooldown)
+	if _, err := client.GetOrderAccrual(ctx, "1"); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe error = %v, want server error", err)
+	}
+	if _, err := client.GetOrderAccrual(ctx, "1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error = %v, want ErrCircuitOpen after failed probe", err)
+	}
+
+	// удачная пробная попытка замыкает его
+	healthy.Store(true)
+	time.Sleep(testOptions().BreakerCooldown)
+	for i := 0; i < 2; i++ {
+		res, err := client.GetOrderAccrual(ctx, "1")
+		if err != nil {
+			t.Fatalf("call %d after recovery: %v", i, err)
+		}
+		if res.Status != models.AccrualStatusProcessing {
+			t.Errorf("unexpected response %+v", res)
+		}
+	}
+}
+
+func TestGetOrderAccrualMalformedBody(t *testing.T) {
+	client, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"order":`))
+	})
+
+	if _, err := client.GetOrderAccrual(context.Background(), "1"); err == nil {
+		t.Fatal("expected decode error")
+	}
+	if *calls != 3 {
+		t.Errorf("malformed body must be retried, got %d requests", *calls)
+	}
+}
+
+func TestGetOrderAccrualCancelled(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetOrderAccrual(ctx, "1"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("error = %v, want context.Canceled", err)
+		}
+	}
+	// отмена на стороне gophermart не размыкает предохранитель
+	if wait := client.breaker.Allow(); wait != 0 {
+		t.Errorf("breaker opened after cancellations, wait %v", wait)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("3"); d != 3*time.Second {
+		t.Errorf("seconds: got %v", d)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(date); d <= 50*time.Second || d > time.Minute {
+		t.Errorf("http date: got %v", d)
+	}
+	if d := parseRetryAfter("soon"); d != defaultRateLimitRetry {
+		t.Errorf("garbage: got %v", d)
+	}
+}
@@ -0,0 +1,24 @@
+package accrual
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrOrderNotRegistered = errors.New("order is not registered in accrual system")
+var ErrRateLimited = errors.New("accrual system rate limit exceeded")
+var ErrCircuitOpen = errors.New("accrual system circuit breaker is open")
+
+// RetryAfterError сообщает, через сколько система начислений готова принимать запросы снова.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error() + ", retry after " + e.RetryAfter.String()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,63 @@
+package accrual
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+// FakeClient отдаёт заранее заданные ответы без сети; незаданные заказы считаются
+// незарегистрированными.
+type FakeClient struct {
+	mu        sync.Mutex
+	responses map[string]models.AccrualResponse
+	errs      map[string]error
+	calls     map[string]int
+}
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		responses: make(map[string]models.AccrualResponse),
+		errs:      make(map[string]error),
+		calls:     make(map[string]int),
+	}
+}
+
+func (f *FakeClient) SetResponse(orderNumber string, res models.AccrualResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	res.Order = orderNumber
+	f.responses[orderNumber] = res
+	delete(f.errs, orderNumber)
+}
+
+func (f *FakeClient) SetError(orderNumber string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errs[orderNumber] = err
+}
+
+func (f *FakeClient) Calls(orderNumber string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls[orderNumber]
+}
+
+func (f *FakeClient) GetOrderAccrual(_ context.Context, orderNumber string) (*models.AccrualResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[orderNumber]++
+	if err, ok := f.errs[orderNumber]; ok {
+		return nil, err
+	}
+	res, ok := f.responses[orderNumber]
+	if !ok {
+		return nil, ErrOrderNotRegistered
+	}
+	return &res, nil
+}
@@ -0,0 +1,37 @@
+package async
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/services"
+)
+
+// StartPointsExpirer периодически списывает баллы, срок которых истёк.
+func StartPointsExpirer(ctx context.Context, svc *services.Service, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expirePoints(ctx, svc)
+			}
+		}
+	}()
+}
+
+func expirePoints(ctx context.Context, svc *services.Service) {
+	users, total, err := svc.ExpirePoints(ctx)
+	if err != nil {
+		log.Printf("points expiry failed after %d users: %v", users, err)
+		return
+	}
+	if users > 0 {
+		log.Printf("points expiry: %s points expired for %d users", total, users)
+	}
+}
@@ -0,0 +1,45 @@
+package async
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/services"
+)
+
+// StartBalanceReconciler периодически сверяет users.balance/withdrawn с журналом проводок
+// и пишет в лог найденные расхождения.
+func StartBalanceReconciler(ctx context.Context, svc *services.Service, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reconcileBalances(ctx, svc)
+			}
+		}
+	}()
+}
+
+func reconcileBalances(ctx context.Context, svc *services.Service) {
+	report, err := svc.ReconcileBalances(ctx)
+	if err != nil {
+		log.Printf("balance reconciliation failed: %v", err)
+		return
+	}
+	for _, d := range report.Drifts {
+		log.Printf("balance drift for user %s: balance cached %s ledger %s, withdrawn cached %s ledger %s",
+			d.UserID, d.CachedBalance, d.LedgerBalance, d.CachedWithdrawn, d.LedgerWithdrawn)
+	}
+	for _, txID := range report.UnbalancedTxIDs {
+		log.Printf("unbalanced ledger transaction %s", txID)
+	}
+	if len(report.Drifts) == 0 && len(report.UnbalancedTxIDs) == 0 {
+		log.Println("balance reconciliation: no drift")
+	}
+}
@@ -0,0 +1,32 @@
+package async
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/services"
+)
+
+// StartAuditSealer периодически дописывает новые записи журнала аудита в цепочку хешей.
+func StartAuditSealer(ctx context.Context, svc *services.Service, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sealAuditChain(ctx, svc)
+			}
+		}
+	}()
+}
+
+func sealAuditChain(ctx context.Context, svc *services.Service) {
+	if n, err := svc.SealAuditChain(ctx); err != nil {
+		log.Printf("audit chain sealing failed after %d records: %v", n, err)
+	}
+}
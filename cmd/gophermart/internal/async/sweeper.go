@@ -0,0 +1,40 @@
+package async
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/services"
+)
+
+// StartPendingOrdersSweeper сразу при старте возвращает в очередь заказы в статусах NEW/PROCESSING,
+// оставшиеся после прошлого запуска, а затем периодически повторяет проход.
+func StartPendingOrdersSweeper(ctx context.Context, svc *services.Service, interval time.Duration) {
+	go func() {
+		sweepPendingOrders(ctx, svc, "recovery")
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sweepPendingOrders(ctx, svc, "sweep")
+			}
+		}
+	}()
+}
+
+func sweepPendingOrders(ctx context.Context, svc *services.Service, phase string) {
+	enqueued, err := svc.EnqueuePendingOrders(ctx)
+	if err != nil {
+		log.Printf("pending orders %s failed: %v", phase, err)
+		return
+	}
+	if enqueued > 0 {
+		log.Printf("pending orders %s: %d orders enqueued", phase, enqueued)
+	}
+}
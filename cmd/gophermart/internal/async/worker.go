@@ -1,17 +1,54 @@
 package async
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/services"
 )
 
-func StartOrderWorker(orderQueue <-chan string, svc *services.Service) {
-	go func() {
-		log.Println("⚙️ order worker started")
-		for orderNumber := range orderQueue {
-			log.Printf("📦 processing order from queue: %s", orderNumber)
-			svc.ProcessAccrual(orderNumber)
+const jobPollInterval = time.Second
+
+// StartOrderWorkers запускает пул из workers воркеров. Воркер сначала арендует задачу из
+// accrual_jobs и только для неё ждёт разрешения общего лимитера перед запросом к системе
+// начислений, поэтому пустая очередь не расходует токены лимитера. Если пауза после 429
+// дольше аренды, задачу может взять другой воркер — повторный опрос безопасен, так как
+// статус заказа меняется через compare-and-set.
+func StartOrderWorkers(ctx context.Context, svc *services.Service, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go runOrderWorker(ctx, svc, i+1)
+	}
+}
+
+func runOrderWorker(ctx context.Context, svc *services.Service, id int) {
+	log.Printf("⚙️ order worker %d started", id)
+	for {
+		jobs, err := svc.LeaseAccrualJobs(ctx, 1)
+		if err != nil {
+			log.Printf("worker %d: failed to lease accrual jobs: %v", id, err)
+		}
+
+		for _, job := range jobs {
+			// аренда истечёт сама, если воркер остановят во время ожидания
+			if err := svc.WaitAccrualSlot(ctx); err != nil {
+				return
+			}
+			log.Printf("📦 worker %d: processing order from queue: %s", id, job.OrderNumber)
+			svc.ProcessAccrualJob(ctx, job)
+		}
+
+		if len(jobs) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jobPollInterval):
 		}
-	}()
+	}
 }
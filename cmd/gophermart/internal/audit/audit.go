@@ -0,0 +1,233 @@
+// Package audit связывает записи журнала аудита с запросом, в котором они сделаны,
+// и выстраивает записи в цепочку хешей.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+type metaKey struct{}
+
+// Meta — сведения о запросе, которые попадают в каждую запись журнала.
+type Meta struct {
+	ActorID   *uuid.UUID
+	IP        string
+	RequestID string
+}
+
+func WithRequest(ctx context.Context, requestID, ip string) context.Context {
+	meta := FromContext(ctx)
+	meta.RequestID, meta.IP = requestID, ip
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+func WithActor(ctx context.Context, actorID uuid.UUID) context.Context {
+	meta := FromContext(ctx)
+	meta.ActorID = &actorID
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+func FromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(metaKey{}).(Meta)
+	return meta
+}
+
+// NewEvent заготавливает запись с данными запроса из ctx и временем, округлённым
+// до точности TIMESTAMP в PostgreSQL, чтобы хеш сходился после чтения из базы.
+func NewEvent(ctx context.Context, e models.AuditEvent) models.AuditEvent {
+	meta := FromContext(ctx)
+	if e.ActorID == nil {
+		e.ActorID = meta.ActorID
+	}
+	if e.IP == "" {
+		e.IP = meta.IP
+	}
+	if e.RequestID == "" {
+		e.RequestID = meta.RequestID
+	}
+	e.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	return e
+}
+
+// LedgerEvent описывает проводку по балансу пользователя; after — баланс после проводки.
+func LedgerEvent(p models.LedgerPosting, txID uuid.UUID, after models.Points) models.AuditEvent {
+	before := after - p.Amount
+	e := models.AuditEvent{
+		Action:        models.AuditMoneyPrefix + p.EntryType,
+		UserID:        &p.UserID,
+		Target:        p.OrderNumber,
+		BalanceBefore: &before,
+		BalanceAfter:  &after,
+		Details:       map[string]interface{}{"amount": p.Amount.String(), "tx_id": txID.String()},
+	}
+	if p.AdjustmentID != nil {
+		e.Target = p.AdjustmentID.String()
+	}
+	return e
+}
+
+// LoginEvent описывает вход: создание новой сессии.
+func LoginEvent(info models.SessionInfo, sessionID uuid.UUID) models.AuditEvent {
+	return models.AuditEvent{
+		Action:  models.AuditLogin,
+		UserID:  &info.UserID,
+		Target:  sessionID.String(),
+		IP:      info.IP,
+		Details: map[string]interface{}{"device": info.Device, "user_agent": info.UserAgent},
+	}
+}
+
+func SessionsRevokedEvent(userID uuid.UUID, sessionIDs []uuid.UUID) models.AuditEvent {
+	ids := make([]string, 0, len(sessionIDs))
+	for _, id := range sessionIDs {
+		ids = append(ids, id.String())
+	}
+	return models.AuditEvent{
+		Action:  models.AuditSessionsRevoked,
+		UserID:  &userID,
+		Details: map[string]interface{}{"sessions": ids},
+	}
+}
+
+func APIKeyCreatedEvent(key models.APIKey) models.AuditEvent {
+	return models.AuditEvent{
+		Action:  models.AuditAPIKeyCreated,
+		UserID:  &key.UserID,
+		Target:  key.ID.String(),
+		Details: map[string]interface{}{"name": key.Name, "prefix": key.Prefix, "scopes": key.Scopes},
+	}
+}
+
+func AdjustmentEvent(action string, adj *models.Adjustment) models.AuditEvent {
+	return models.AuditEvent{
+		Action: action,
+		UserID: &adj.UserID,
+		Target: adj.ID.String(),
+		Details: map[string]interface{}{
+			"amount": adj.Amount.String(),
+			"reason": adj.Reason,
+		},
+	}
+}
+
+// Seal присоединяет запись к цепочке после prevHash. ID и CreatedAt должны быть уже назначены.
+func Seal(e *models.AuditEvent, prevHash string) {
+	e.PrevHash = prevHash
+	e.Hash = Hash(*e)
+}
+
+// Hash считает SHA-256 от канонического представления записи вместе с PrevHash.
+func Hash(e models.AuditEvent) string {
+	data, _ := json.Marshal(struct {
+		PrevHash      string          `json:"prev_hash"`
+		ID            int64           `json:"id"`
+		Action        string          `json:"action"`
+		ActorID       string          `json:"actor_id"`
+		UserID        string          `json:"user_id"`
+		Target        string          `json:"target"`
+		IP            string          `json:"ip"`
+		RequestID     string          `json:"request_id"`
+		BalanceBefore string          `json:"balance_before"`
+		BalanceAfter  string          `json:"balance_after"`
+		Details       json.RawMessage `json:"details"`
+		CreatedAt     string          `json:"created_at"`
+	}{
+		PrevHash:      e.PrevHash,
+		ID:            e.ID,
+		Action:        e.Action,
+		ActorID:       uuidString(e.ActorID),
+		UserID:        uuidString(e.UserID),
+		Target:        e.Target,
+		IP:            e.IP,
+		RequestID:     e.RequestID,
+		BalanceBefore: pointsString(e.BalanceBefore),
+		BalanceAfter:  pointsString(e.BalanceAfter),
+		Details:       canonicalDetails(e.Details),
+		CreatedAt:     e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalDetails приводит детали к виду, в котором они вернутся из JSONB: ключи
+// отсортированы, числа — float64. Пустые детали хранятся как NULL.
+func canonicalDetails(details map[string]interface{}) json.RawMessage {
+	if len(details) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil
+	}
+	data, _ = json.Marshal(normalized)
+	return data
+}
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func pointsString(p *models.Points) string {
+	if p == nil {
+		return ""
+	}
+	return p.String()
+}
+
+// Verifier проверяет цепочку, получая записи по порядку, начиная с самой первой.
+type Verifier struct {
+	prev string
+	res  models.AuditVerification
+}
+
+func NewVerifier() *Verifier {
+	return &Verifier{}
+}
+
+// Add проверяет очередную запись; false — цепочка уже разорвана и дальше проверять незачем.
+func (v *Verifier) Add(e models.AuditEvent) bool {
+	if v.res.BrokenAt != nil {
+		return false
+	}
+	v.res.Checked++
+	switch {
+	case e.PrevHash != v.prev:
+		v.broken(e.ID, "prev_hash does not match the previous record")
+	case e.Hash != Hash(e):
+		v.broken(e.ID, "hash does not match the record contents")
+	default:
+		v.prev, v.res.LastID = e.Hash, e.ID
+		return true
+	}
+	return false
+}
+
+// Finish сверяет последнюю проверенную запись с головой цепочки: так обнаруживается
+// удаление записей с конца журнала.
+func (v *Verifier) Finish(headID int64, headHash string) models.AuditVerification {
+	if v.res.BrokenAt == nil && (v.prev != headHash || v.res.LastID != headID) {
+		v.broken(headID, "chain head does not match the last record")
+	}
+	v.res.Valid = v.res.BrokenAt == nil
+	return v.res
+}
+
+func (v *Verifier) broken(id int64, reason string) {
+	v.res.BrokenAt = &id
+	v.res.Reason = reason
+}
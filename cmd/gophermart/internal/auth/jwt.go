@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+	ErrKeyRetired   = errors.New("token signed with retired key")
+)
+
+// Claims — полезная нагрузка access-токена; SessionID — сессия, открытая при входе.
+type Claims struct {
+	Subject   string `json:"sub"`
+	SessionID string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// Signer выпускает и проверяет JWT, подписанные HS256 ключами из Keyring;
+// идентификатор ключа передаётся в заголовке токена (kid).
+type Signer struct {
+	keys *Keyring
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func NewSigner(keys *Keyring, ttl time.Duration) *Signer {
+	return &Signer{keys: keys, ttl: ttl, now: time.Now}
+}
+
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+func (s *Signer) Issue(userID, sessionID string) (string, time.Time, error) {
+	now := s.now()
+	expiresAt := now.Add(s.ttl)
+	claims := Claims{
+		Subject:   userID,
+		SessionID: sessionID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	key := s.keys.active
+	h, err := json.Marshal(header{Alg: "HS256", Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	p, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	unsigned := encodeSegment(h) + "." + encodeSegment(p)
+	return unsigned + "." + encodeSegment(sign(key, unsigned)), expiresAt, nil
+}
+
+func (s *Signer) Parse(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var h header
+	if err := unmarshalSegment(parts[0], &h); err != nil || h.Alg != "HS256" {
+		return nil, ErrInvalidToken
+	}
+	key, ok := s.keys.keys[h.Kid]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	sig, err := decodeSegment(parts[2])
+	if err != nil || !hmac.Equal(sig, sign(key, parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidToken
+	}
+	if key.Retired {
+		return nil, ErrKeyRetired
+	}
+	var claims Claims
+	if err := unmarshalSegment(parts[1], &claims); err != nil || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if !s.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ErrTokenExpired
+	}
+	return &claims, nil
+}
+
+func sign(key Key, unsigned string) []byte {
+	mac := hmac.New(sha256.New, []byte(key.Secret))
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func unmarshalSegment(s string, v interface{}) error {
+	b, err := decodeSegment(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
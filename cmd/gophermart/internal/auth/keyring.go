@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrNoActiveKey = errors.New("keyring has no active signing key")
+
+// Key — ключ подписи токенов. Выведенный из оборота (Retired) ключ больше не принимается,
+// но остаётся в наборе, чтобы такие токены отличались от подделанных в логах.
+type Key struct {
+	ID      string `json:"id"`
+	Secret  string `json:"secret"`
+	Retired bool   `json:"retired"`
+}
+
+// Keyring — набор ключей подписи. Новые токены подписываются самым новым действующим ключом,
+// то есть последним в списке; проверяются любым действующим.
+type Keyring struct {
+	keys   map[string]Key
+	active Key
+}
+
+func NewKeyring(keys []Key) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string]Key, len(keys))}
+	for _, k := range keys {
+		if k.ID == "" || k.Secret == "" {
+			return nil, errors.New("key id and secret must not be empty")
+		}
+		if _, ok := kr.keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		kr.keys[k.ID] = k
+		if !k.Retired {
+			kr.active = k
+		}
+	}
+	if kr.active.ID == "" {
+		return nil, ErrNoActiveKey
+	}
+	return kr, nil
+}
+
+// LoadKeyringFile читает JSON вида {"keys": [{"id": "...", "secret": "...", "retired": false}]}.
+func LoadKeyringFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Keys []Key `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return NewKeyring(file.Keys)
+}
+
+// ParseKeyring разбирает строку вида "kid1:secret1:retired,kid2:secret2".
+func ParseKeyring(spec string) (*Keyring, error) {
+	var keys []Key
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		switch {
+		case len(parts) == 2:
+			keys = append(keys, Key{ID: parts[0], Secret: parts[1]})
+		case len(parts) == 3 && parts[2] == "retired":
+			keys = append(keys, Key{ID: parts[0], Secret: parts[1], Retired: true})
+		default:
+			return nil, fmt.Errorf("invalid key %q, expected kid:secret[:retired]", parts[0])
+		}
+	}
+	return NewKeyring(keys)
+}
+
+// ActiveKeyID возвращает идентификатор ключа, которым подписываются новые токены.
+func (kr *Keyring) ActiveKeyID() string {
+	return kr.active.ID
+}
+
+// HasSecret сообщает, используется ли secret хотя бы в одном ключе набора.
+func (kr *Keyring) HasSecret(secret string) bool {
+	for _, k := range kr.keys {
+		if k.Secret == secret {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewToken возвращает случайный непрозрачный токен (refresh, сброс пароля) для клиента
+// и его хеш для хранения в базе.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix отличает API-ключи от прочих токенов; по началу ключа владелец
+// узнаёт его в списке, поэтому в хранилище кроме хеша сохраняется и префикс.
+const APIKeyPrefix = "gm_"
+
+// NewAPIKey возвращает ключ, его видимое начало и хеш для хранения.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	token, _, err := NewToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + token
+	return key, key[:len(APIKeyPrefix)+8], HashToken(key), nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// AdminProposeAdjustment: POST /api/admin/users/:id/adjustments
+// {"type": "credit", "amount": 100, "reason": "goodwill", "comment": "..."}
+func (h *Handler) AdminProposeAdjustment(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req models.AdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	adj, err := h.service.ProposeAdjustment(c.Request.Context(), actorID, c.Param("id"), req)
+	switch {
+	case errors.Is(err, customerrors.ErrInvalidAdjustment):
+		c.String(http.StatusBadRequest, err.Error())
+	case errors.Is(err, customerrors.ErrUserNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	case err != nil:
+		log.Printf("ProposeAdjustment error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	default:
+		c.JSON(http.StatusCreated, adj)
+	}
+}
+
+// AdminGetAdjustments: GET /api/admin/adjustments?status=pending&user_id=...&limit=
+func (h *Handler) AdminGetAdjustments(c *gin.Context) {
+	var filter models.AdjustmentFilter
+	switch status := c.Query("status"); status {
+	case "", models.AdjustmentPending, models.AdjustmentApproved, models.AdjustmentRejected:
+		filter.Status = status
+	default:
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		uid, err := uuid.Parse(raw)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		filter.UserID = &uid
+	}
+	limit, cursor, err := parsePage(c)
+	if err != nil || cursor != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	filter.Limit = limit
+
+	list, err := h.service.ListAdjustments(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("ListAdjustments error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if len(list) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AdminApproveAdjustment: POST /api/admin/adjustments/:id/approve — применяет корректировку к балансу.
+func (h *Handler) AdminApproveAdjustment(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	adj, err := h.service.ApproveAdjustment(c.Request.Context(), actorID, c.Param("id"))
+	respondAdjustmentDecision(c, adj, err)
+}
+
+// AdminRejectAdjustment: POST /api/admin/adjustments/:id/reject
+func (h *Handler) AdminRejectAdjustment(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	adj, err := h.service.RejectAdjustment(c.Request.Context(), actorID, c.Param("id"))
+	respondAdjustmentDecision(c, adj, err)
+}
+
+func respondAdjustmentDecision(c *gin.Context, adj *models.Adjustment, err error) {
+	switch {
+	case errors.Is(err, customerrors.ErrAdjustmentNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, customerrors.ErrSelfApproval):
+		c.String(http.StatusForbidden, err.Error())
+	case errors.Is(err, customerrors.ErrAdjustmentNotPending), errors.Is(err, customerrors.ErrInsufficientBalance):
+		c.String(http.StatusConflict, err.Error())
+	case err != nil:
+		log.Printf("adjustment decision error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	default:
+		c.JSON(http.StatusOK, adj)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// AdminFindUser: GET /api/admin/users?login=...
+func (h *Handler) AdminFindUser(c *gin.Context) {
+	login := c.Query("login")
+	if login == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	info, err := h.service.FindUser(c.Request.Context(), login)
+	h.respondUserInfo(c, info, err)
+}
+
+// AdminGetUser: GET /api/admin/users/:id
+func (h *Handler) AdminGetUser(c *gin.Context) {
+	info, err := h.service.GetUserInfo(c.Request.Context(), c.Param("id"))
+	h.respondUserInfo(c, info, err)
+}
+
+func (h *Handler) respondUserInfo(c *gin.Context, info *models.UserInfo, err error) {
+	if errors.Is(err, customerrors.ErrUserNotFound) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("admin user lookup error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// AdminGetOrders: GET /api/admin/users/:id/orders — те же параметры, что у /api/user/orders.
+func (h *Handler) AdminGetOrders(c *gin.Context) {
+	if userID, ok := h.adminTargetUser(c); ok {
+		h.respondOrders(c, userID)
+	}
+}
+
+// AdminGetWithdrawals: GET /api/admin/users/:id/withdrawals
+func (h *Handler) AdminGetWithdrawals(c *gin.Context) {
+	if userID, ok := h.adminTargetUser(c); ok {
+		h.respondWithdrawals(c, userID)
+	}
+}
+
+// AdminGetBalance: GET /api/admin/users/:id/balance
+func (h *Handler) AdminGetBalance(c *gin.Context) {
+	if userID, ok := h.adminTargetUser(c); ok {
+		h.respondBalance(c, userID)
+	}
+}
+
+// adminTargetUser проверяет, что пользователь из пути существует.
+func (h *Handler) adminTargetUser(c *gin.Context) (string, bool) {
+	info, err := h.service.GetUserInfo(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, customerrors.ErrUserNotFound) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return "", false
+	}
+	if err != nil {
+		log.Printf("admin user lookup error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return "", false
+	}
+	return info.ID.String(), true
+}
+
+// AdminBlockUser: POST /api/admin/users/:id/block — блокирует вход и отзывает все сессии.
+func (h *Handler) AdminBlockUser(c *gin.Context) {
+	h.setUserBlocked(c, true)
+}
+
+// AdminUnblockUser: POST /api/admin/users/:id/unblock
+func (h *Handler) AdminUnblockUser(c *gin.Context) {
+	h.setUserBlocked(c, false)
+}
+
+func (h *Handler) setUserBlocked(c *gin.Context, blocked bool) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	err := h.service.SetUserBlocked(c.Request.Context(), actorID, c.Param("id"), blocked)
+	h.respondAdminUpdate(c, err)
+}
+
+// AdminSetRole: PUT /api/admin/users/:id/role {"role": "support"}
+func (h *Handler) AdminSetRole(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	err := h.service.SetUserRole(c.Request.Context(), actorID, c.Param("id"), req.Role)
+	h.respondAdminUpdate(c, err)
+}
+
+func (h *Handler) respondAdminUpdate(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, customerrors.ErrUserNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, customerrors.ErrInvalidRole):
+		c.String(http.StatusBadRequest, err.Error())
+	case errors.Is(err, customerrors.ErrSelfModification):
+		c.String(http.StatusConflict, err.Error())
+	case err != nil:
+		log.Printf("admin update error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	default:
+		c.Status(http.StatusOK)
+	}
+}
+
+// AdminRetriggerAccrual: POST /api/admin/orders/:number/accrual — немедленно возобновляет опрос
+// системы начислений по незавершённому заказу.
+func (h *Handler) AdminRetriggerAccrual(c *gin.Context) {
+	err := h.service.RetriggerAccrual(c.Request.Context(), c.Param("number"))
+	switch {
+	case errors.Is(err, customerrors.ErrOrderNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, customerrors.ErrOrderAlreadyFinal):
+		c.String(http.StatusConflict, err.Error())
+	case err != nil:
+		log.Printf("RetriggerAccrual error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	default:
+		c.Status(http.StatusAccepted)
+	}
+}
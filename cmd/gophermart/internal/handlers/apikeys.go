@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// CreateAPIKey: POST /api/user/api-keys {"name": "...", "scopes": ["orders:write"]} — ключ
+// возвращается в ответе единственный раз.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req models.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(c.Request.Context(), userID, req)
+	if errors.Is(err, customerrors.ErrInvalidAPIKeyRequest) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("CreateAPIKey error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// GetAPIKeys: GET /api/user/api-keys — действующие ключи пользователя без самих ключей.
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.service.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("ListAPIKeys error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if len(keys) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey: DELETE /api/user/api-keys/:id
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.RevokeAPIKey(c.Request.Context(), userID, c.Param("id"))
+	if errors.Is(err, customerrors.ErrAPIKeyNotFound) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("RevokeAPIKey error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusOK)
+}
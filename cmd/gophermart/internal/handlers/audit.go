@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+// AdminGetAudit: GET /api/admin/audit?action=&actor_id=&user_id=&target=&request_id=&from=&to=&limit=&cursor=
+// Записи отдаются от новых к старым, курсор следующей страницы — в заголовке X-Next-Cursor.
+func (h *Handler) AdminGetAudit(c *gin.Context) {
+	filter := models.AuditFilter{
+		Action:    c.Query("action"),
+		Target:    c.Query("target"),
+		RequestID: c.Query("request_id"),
+	}
+	var err error
+	if filter.ActorID, err = parseUUIDParam(c, "actor_id"); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if filter.UserID, err = parseUUIDParam(c, "user_id"); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if filter.From, filter.To, err = parseTimeRange(c, "from", "to"); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if filter.Limit, filter.Cursor, err = parsePage(c); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	list, next, err := h.service.ListAuditEvents(c.Request.Context(), filter)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("ListAuditEvents error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if next != "" {
+		c.Header(nextCursorHeader, next)
+	}
+	if len(list) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AdminVerifyAudit: GET /api/admin/audit/verify — пересчитывает цепочку хешей журнала.
+// Разрыв цепочки — не ошибка запроса: ответ 200 с valid=false и номером первой неверной записи.
+func (h *Handler) AdminVerifyAudit(c *gin.Context) {
+	res, err := h.service.VerifyAuditChain(c.Request.Context())
+	if err != nil {
+		log.Printf("VerifyAuditChain error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !res.Valid {
+		log.Printf("audit chain is broken at %d: %s", *res.BrokenAt, res.Reason)
+	}
+	c.JSON(http.StatusOK, res)
+}
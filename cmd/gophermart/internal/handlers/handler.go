@@ -3,32 +3,31 @@ package handlers
 import (
 	// "io"
 
+	"errors"
 	"io"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 
-	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/middlewares"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/services"
 )
 
 type Handler struct {
-	service   *services.Service
-	secretKey string
+	service *services.Service
 }
 
-func NewHandler(service *services.Service, secretKey string) *Handler {
-	return &Handler{service: service, secretKey: secretKey}
+func NewHandler(service *services.Service) *Handler {
+	return &Handler{service: service}
 }
 
 type AuthRequest struct {
 	Login    string `json:"login" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Device   string `json:"device"`
 }
 
 func (h *Handler) Register(c *gin.Context) {
@@ -38,14 +37,28 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := h.service.CreateUser(req.Login, req.Password)
-	if err != nil {
+	// перебор логинов через регистрацию ограничиваем по адресу клиента
+	ip := c.ClientIP()
+	if !h.allowAuthAttempt(c, "", ip) {
+		return
+	}
+
+	user, err := h.service.CreateUser(c.Request.Context(), req.Login, req.Password)
+	switch {
+	case errors.Is(err, customerrors.ErrWeakPassword):
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, customerrors.ErrLoginAlreadyExists):
+		h.recordAuthFailure(c, "", ip)
 		c.Status(http.StatusConflict)
 		return
+	case err != nil:
+		log.Printf("CreateUser error: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
 	}
 
-	middlewares.SetAuthCookie(c, user.ID, h.secretKey)
-	c.Status(http.StatusOK)
+	h.issueTokens(c, user.ID, req.Device)
 }
 
 func (h *Handler) Login(c *gin.Context) {
@@ -55,20 +68,43 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.service.GetUserByLogin(req.Login)
-	if err != nil {
+	ip := c.ClientIP()
+	if !h.allowAuthAttempt(c, req.Login, ip) {
+		return
+	}
+
+	user, err := h.service.Authenticate(c.Request.Context(), req.Login, req.Password)
+	if errors.Is(err, customerrors.ErrInvalidCredentials) {
+		h.recordAuthFailure(c, req.Login, ip)
 		c.Status(http.StatusUnauthorized)
 		return
 	}
+	if errors.Is(err, customerrors.ErrUserBlocked) {
+		c.Status(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Printf("Authenticate error: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+	challenge, err := h.service.BeginLogin(c.Request.Context(), user, req.Device)
 	if err != nil {
-		c.Status(http.StatusUnauthorized)
+		log.Printf("BeginLogin error: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if challenge != nil {
+		// пароль верный, но нужен код второго фактора: POST /api/user/login/2fa
+		c.JSON(http.StatusAccepted, challenge)
 		return
 	}
 
-	middlewares.SetAuthCookie(c, user.ID, h.secretKey)
-	c.Status(http.StatusOK)
+	if err := h.service.RecordAuthSuccess(c.Request.Context(), req.Login); err != nil {
+		log.Printf("RecordAuthSuccess error: %v", err)
+	}
+	h.issueTokens(c, user.ID, req.Device)
 }
 
 func (h *Handler) UploadOrder(c *gin.Context) {
@@ -127,12 +163,33 @@ func (h *Handler) GetOrders(c *gin.Context) {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
-	orders, err := h.service.GetUserOrders(c.Request.Context(), userID)
-	log.Printf("orders: %v", orders)
+	h.respondOrders(c, userID)
+}
+
+// respondOrders отдаёт страницу заказов пользователя; используется и административным API.
+func (h *Handler) respondOrders(c *gin.Context, userID string) {
+	filter, err := parseListFilter(c, true)
+	if err == nil {
+		filter.Statuses, err = parseListParam(c, "status",
+			models.OrderStatusNew, models.OrderStatusProcessing, models.OrderStatusInvalid, models.OrderStatusProcessed)
+	}
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	orders, next, err := h.service.GetUserOrders(c.Request.Context(), userID, filter)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
+	if next != "" {
+		c.Header(nextCursorHeader, next)
+	}
 	if len(orders) == 0 {
 		c.Status(http.StatusNoContent)
 		return
@@ -160,13 +217,19 @@ func (h *Handler) Withdraw(c *gin.Context) {
 		return
 	}
 
-	err := h.service.Withdraw(c.Request.Context(), userID, req.Order, req.Sum)
+	err := h.service.Withdraw(c.Request.Context(), userID, req.Order, req.Sum, c.GetHeader(otpHeader))
 	if err != nil {
 		switch err {
 		case customerrors.ErrInsufficientBalance:
 			c.AbortWithStatus(http.StatusPaymentRequired)
 		case customerrors.ErrInvalidOrderNumber:
 			c.AbortWithStatus(http.StatusUnprocessableEntity)
+		case customerrors.ErrInvalidAmount:
+			c.AbortWithStatus(http.StatusBadRequest)
+		case customerrors.ErrWithdrawalOrderExists:
+			c.AbortWithStatus(http.StatusConflict)
+		case customerrors.ErrTwoFactorRequired, customerrors.ErrInvalidOTP:
+			c.String(http.StatusForbidden, err.Error())
 		default:
 			c.AbortWithStatus(http.StatusInternalServerError)
 		}
@@ -187,12 +250,29 @@ func (h *Handler) GetWithdrawals(c *gin.Context) {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
+	h.respondWithdrawals(c, userID)
+}
 
-	list, err := h.service.GetWithdrawals(c.Request.Context(), userID)
+func (h *Handler) respondWithdrawals(c *gin.Context, userID string) {
+	filter, err := parseListFilter(c, false)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	list, next, err := h.service.GetWithdrawals(c.Request.Context(), userID, filter)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		// например, курсор от ленты операций
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
+	if next != "" {
+		c.Header(nextCursorHeader, next)
+	}
 
 	if len(list) == 0 {
 		c.Status(http.StatusNoContent)
@@ -213,7 +293,10 @@ func (h *Handler) GetUserBalance(c *gin.Context) {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
+	h.respondBalance(c, userID)
+}
 
+func (h *Handler) respondBalance(c *gin.Context, userID string) {
 	balance, err := h.service.GetUserBalance(c.Request.Context(), userID)
 	if err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
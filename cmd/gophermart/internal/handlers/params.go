@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+const nextCursorHeader = "X-Next-Cursor"
+
+var errInvalidQuery = errors.New("invalid query parameter")
+
+func currentUserID(c *gin.Context) (string, bool) {
+	userIDRaw, exists := c.Get("user_id")
+	if !exists {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return "", false
+	}
+	userID, ok := userIDRaw.(string)
+	if !ok {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return "", false
+	}
+	return userID, true
+}
+
+// parseListParam принимает значения через запятую и повторяющиеся параметры.
+func parseListParam(c *gin.Context, name string, allowed ...string) ([]string, error) {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, v := range strings.Split(raw, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			valid := false
+			for _, a := range allowed {
+				if strings.EqualFold(v, a) {
+					values = append(values, a)
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return nil, errInvalidQuery
+			}
+		}
+	}
+	return values, nil
+}
+
+func parseUUIDParam(c *gin.Context, name string) (*uuid.UUID, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, errInvalidQuery
+	}
+	return &id, nil
+}
+
+func parseTimeRange(c *gin.Context, fromName, toName string) (*time.Time, *time.Time, error) {
+	from, err := parseTimeParam(c, fromName)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := parseTimeParam(c, toName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, errInvalidQuery
+	}
+	return from, to, nil
+}
+
+// parseTimeParam принимает RFC 3339 или дату YYYY-MM-DD и приводит время к UTC, как в базе.
+func parseTimeParam(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, errInvalidQuery
+		}
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+func parsePage(c *gin.Context) (int, *models.Cursor, error) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return 0, nil, errInvalidQuery
+		}
+		limit = n
+	}
+	var cursor *models.Cursor
+	if raw := c.Query("cursor"); raw != "" {
+		cur, err := models.DecodeCursor(raw)
+		if err != nil {
+			return 0, nil, err
+		}
+		cursor = cur
+	}
+	return limit, cursor, nil
+}
+
+// parseListFilter разбирает from, to, limit, cursor и sort=asc|desc.
+func parseListFilter(c *gin.Context, defaultDesc bool) (models.ListFilter, error) {
+	filter := models.ListFilter{Desc: defaultDesc}
+	var err error
+	if filter.From, filter.To, err = parseTimeRange(c, "from", "to"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, filter.Cursor, err = parsePage(c); err != nil {
+		return filter, err
+	}
+	switch strings.ToLower(c.Query("sort")) {
+	case "":
+	case "asc":
+		filter.Desc = false
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, errInvalidQuery
+	}
+	return filter, nil
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/middlewares"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// ChangePassword: POST /api/user/password — остальные сессии пользователя отзываются.
+func (h *Handler) ChangePassword(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req models.PasswordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.ChangePassword(c.Request.Context(), userID, c.GetString("session_id"), req.CurrentPassword, req.NewPassword)
+	switch {
+	case errors.Is(err, customerrors.ErrInvalidCredentials):
+		c.AbortWithStatus(http.StatusForbidden)
+	case errors.Is(err, customerrors.ErrWeakPassword):
+		c.String(http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("ChangePassword error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	default:
+		c.Status(http.StatusOK)
+	}
+}
+
+// RequestPasswordReset: POST /api/user/password/reset — ответ не зависит от того,
+// существует ли логин.
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	ip := c.ClientIP()
+	if !h.allowAuthAttempt(c, req.Login, ip) {
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(c.Request.Context(), req.Login); err != nil {
+		log.Printf("RequestPasswordReset error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset: POST /api/user/password/reset/confirm — все сессии пользователя отзываются.
+func (h *Handler) ConfirmPasswordReset(c *gin.Context) {
+	var req models.PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	ip := c.ClientIP()
+	if !h.allowAuthAttempt(c, "", ip) {
+		return
+	}
+
+	err := h.service.ConfirmPasswordReset(c.Request.Context(), req.Token, req.NewPassword)
+	switch {
+	case errors.Is(err, customerrors.ErrInvalidResetToken):
+		h.recordAuthFailure(c, "", ip)
+		c.AbortWithStatus(http.StatusUnauthorized)
+	case errors.Is(err, customerrors.ErrWeakPassword):
+		c.String(http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("ConfirmPasswordReset error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	default:
+		middlewares.ClearAuthCookies(c)
+		c.Status(http.StatusOK)
+	}
+}
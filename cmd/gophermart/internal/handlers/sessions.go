@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/middlewares"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// GetSessions: GET /api/user/sessions — активные сессии пользователя.
+func (h *Handler) GetSessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.service.ListSessions(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil {
+		log.Printf("ListSessions error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if len(sessions) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession: DELETE /api/user/sessions/:id
+func (h *Handler) RevokeSession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Param("id")
+	err := h.service.RevokeSession(c.Request.Context(), userID, sessionID)
+	if errors.Is(err, customerrors.ErrSessionNotFound) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("RevokeSession error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if sessionID == c.GetString("session_id") {
+		middlewares.ClearAuthCookies(c)
+	}
+	c.Status(http.StatusOK)
+}
+
+// RevokeSessions: DELETE /api/user/sessions[?keep_current=true] — отзывает все сессии,
+// либо все, кроме текущей.
+func (h *Handler) RevokeSessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	keep := ""
+	if c.Query("keep_current") == "true" {
+		keep = c.GetString("session_id")
+	}
+	revoked, err := h.service.RevokeOtherSessions(c.Request.Context(), userID, keep)
+	if err != nil {
+		log.Printf("RevokeSessions error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if keep == "" {
+		middlewares.ClearAuthCookies(c)
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/middlewares"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+const maxDeviceNameLength = 100
+
+// allowAuthAttempt отвечает 429 с Retry-After, если попытки входа для логина или адреса
+// временно запрещены.
+func (h *Handler) allowAuthAttempt(c *gin.Context, login, ip string) bool {
+	wait, err := h.service.CheckAuthAttempt(c.Request.Context(), login, ip)
+	if err != nil {
+		log.Printf("CheckAuthAttempt error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return false
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.AbortWithStatus(http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) recordAuthFailure(c *gin.Context, login, ip string) {
+	if err := h.service.RecordAuthFailure(c.Request.Context(), login, ip); err != nil {
+		log.Printf("RecordAuthFailure error: %v", err)
+	}
+}
+
+// issueTokens открывает сессию после успешной регистрации или входа и выдаёт пару токенов:
+// в cookie, в заголовке Authorization и в теле ответа.
+func (h *Handler) issueTokens(c *gin.Context, userID uuid.UUID, device string) {
+	if len(device) > maxDeviceNameLength {
+		device = device[:maxDeviceNameLength]
+	}
+	pair, err := h.service.IssueTokens(c.Request.Context(), models.SessionInfo{
+		UserID:    userID,
+		Device:    device,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		log.Printf("IssueTokens error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	middlewares.SetAuthCookies(c, pair)
+	c.JSON(http.StatusOK, pair)
+}
+
+// RefreshTokens: POST /api/user/token/refresh, токен берётся из cookie или из тела {"refresh_token": "..."}.
+func (h *Handler) RefreshTokens(c *gin.Context) {
+	token := middlewares.RefreshTokenFromCookie(c)
+	if token == "" {
+		var req models.RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		token = req.RefreshToken
+	}
+
+	pair, err := h.service.RefreshTokens(c.Request.Context(), token)
+	switch {
+	case errors.Is(err, customerrors.ErrRefreshTokenReused):
+		log.Printf("refresh token reuse detected, session revoked")
+		fallthrough
+	case errors.Is(err, customerrors.ErrInvalidRefreshToken):
+		middlewares.ClearAuthCookies(c)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	case err != nil:
+		log.Printf("RefreshTokens error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	middlewares.SetAuthCookies(c, pair)
+	c.JSON(http.StatusOK, pair)
+}
+
+// Logout отзывает текущую сессию вместе с её refresh-токенами.
+func (h *Handler) Logout(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	err := h.service.Logout(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil && !errors.Is(err, customerrors.ErrSessionNotFound) {
+		log.Printf("Logout error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	middlewares.ClearAuthCookies(c)
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+// GetTransactions: GET /api/user/transactions?type=accrual,withdrawal,adjustment&from=&to=&limit=&cursor=
+// Курсор следующей страницы возвращается в заголовке X-Next-Cursor.
+func (h *Handler) GetTransactions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	filter := models.TransactionFilter{}
+	var err error
+	if filter.Types, err = parseListParam(c, "type", models.TransactionAccrual, models.TransactionWithdrawal, models.TransactionAdjustment, models.TransactionExpiry); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if filter.From, filter.To, err = parseTimeRange(c, "from", "to"); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if filter.Limit, filter.Cursor, err = parsePage(c); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	list, next, err := h.service.GetTransactions(c.Request.Context(), userID, filter)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if next != "" {
+		c.Header(nextCursorHeader, next)
+	}
+	if len(list) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// otpHeader — заголовок с кодом второго фактора для списаний больше порога.
+const otpHeader = "X-OTP-Code"
+
+// EnrollTOTP: POST /api/user/2fa/enroll — секрет и otpauth-ссылка для приложения-аутентификатора.
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.service.EnrollTOTP(c.Request.Context(), userID)
+	if errors.Is(err, customerrors.ErrTOTPAlreadyEnabled) {
+		c.AbortWithStatus(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("EnrollTOTP error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP: POST /api/user/2fa/confirm {"code": "123456"} — включает второй фактор
+// и единственный раз возвращает коды восстановления.
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req models.OTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.service.ConfirmTOTP(c.Request.Context(), userID, req.Code)
+	switch {
+	case errors.Is(err, customerrors.ErrTOTPNotEnrolled):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, customerrors.ErrTOTPAlreadyEnabled):
+		c.AbortWithStatus(http.StatusConflict)
+	case errors.Is(err, customerrors.ErrInvalidOTP):
+		c.AbortWithStatus(http.StatusUnprocessableEntity)
+	case err != nil:
+		log.Printf("ConfirmTOTP error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	default:
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
+
+// CompleteLogin: POST /api/user/login/2fa {"challenge_token": "...", "code": "..."} — второй шаг входа,
+// принимает код из приложения или код восстановления.
+func (h *Handler) CompleteLogin(c *gin.Context) {
+	var req models.LoginChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+
+	pending, err := h.service.LoginChallenge(ctx, req.ChallengeToken)
+	if errors.Is(err, customerrors.ErrInvalidLoginChallenge) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("LoginChallenge error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !h.allowAuthAttempt(c, pending.Login, ip) {
+		return
+	}
+
+	ch, err := h.service.CompleteLogin(ctx, req.ChallengeToken, req.Code)
+	switch {
+	case errors.Is(err, customerrors.ErrInvalidOTP):
+		h.recordAuthFailure(c, pending.Login, ip)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	case errors.Is(err, customerrors.ErrInvalidLoginChallenge):
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	case errors.Is(err, customerrors.ErrUserBlocked):
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	case err != nil:
+		log.Printf("CompleteLogin error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.RecordAuthSuccess(ctx, ch.Login); err != nil {
+		log.Printf("RecordAuthSuccess error: %v", err)
+	}
+	h.issueTokens(c, ch.UserID, ch.Device)
+}
@@ -1,61 +1,194 @@
 package middlewares
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-)
 
-const cookieName = "user_id"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
 
-func signUserID(userID string, secretKey string) string {
-	h := hmac.New(sha256.New, []byte(secretKey))
-	h.Write([]byte(userID))
-	return hex.EncodeToString(h.Sum(nil))
-}
+const (
+	accessCookieName  = "access_token"
+	refreshCookieName = "refresh_token"
+	// refresh-токен нужен только эндпоинтам под /api/user/token
+	refreshCookiePath = "/api/user/token"
 
-func validateCookie(userID, signature string, secretKey string) bool {
-	return hmac.Equal([]byte(signUserID(userID, secretKey)), []byte(signature))
-}
+	apiKeyHeader = "X-API-Key"
+	// apiKeyScopesKey есть в контексте только у запросов, авторизованных API-ключом
+	apiKeyScopesKey = "api_key_scopes"
+)
 
-func SetAuthCookie(c *gin.Context, userID uuid.UUID, secretKey string) {
-	signed := userID.String() + "|" + signUserID(userID.String(), secretKey)
+func SetAuthCookies(c *gin.Context, pair *models.TokenPair) {
 	http.SetCookie(c.Writer, &http.Cookie{
-		Name:     "user_id",
-		Value:    signed,
+		Name:     accessCookieName,
+		Value:    pair.AccessToken,
 		HttpOnly: true,
 		Path:     "/",
-		Expires:  time.Now().Add(365 * 24 * time.Hour),
+		Expires:  pair.AccessExpiresAt,
+	})
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    pair.RefreshToken,
+		HttpOnly: true,
+		Path:     refreshCookiePath,
+		Expires:  pair.RefreshExpiresAt,
 	})
+	c.Header("Authorization", pair.TokenType+" "+pair.AccessToken)
+}
+
+func ClearAuthCookies(c *gin.Context) {
+	for name, path := range map[string]string{accessCookieName: "/", refreshCookieName: refreshCookiePath} {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			HttpOnly: true,
+			Path:     path,
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+		})
+	}
+}
+
+// RefreshTokenFromCookie возвращает refresh-токен из cookie или пустую строку.
+func RefreshTokenFromCookie(c *gin.Context) string {
+	cookie, err := c.Request.Cookie(refreshCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// accessToken берёт токен из заголовка Authorization: Bearer, а при его отсутствии — из cookie.
+func accessToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	cookie, err := c.Request.Cookie(accessCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// SessionChecker сообщает, жива ли сессия, к которой привязан access-токен.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// APIKeyChecker находит действующий API-ключ; неизвестный или отозванный — ErrInvalidAPIKey.
+type APIKeyChecker interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
 }
 
-func AuthMiddleware(secretKey string) gin.HandlerFunc {
+// AuthMiddleware принимает access-токен либо API-ключ в заголовке X-API-Key.
+func AuthMiddleware(signer *auth.Signer, sessions SessionChecker, apiKeys APIKeyChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		cookie, err := c.Request.Cookie(cookieName)
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			authenticateAPIKey(c, apiKeys, key)
+			return
+		}
+
+		token := accessToken(c)
+		if token == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := signer.Parse(token)
 		if err != nil {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		parts := strings.Split(cookie.Value, "|")
-		if len(parts) != 2 {
+		active, err := sessions.IsSessionActive(c.Request.Context(), claims.SessionID)
+		if err != nil {
+			log.Printf("session check failed: %v", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if !active {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		userID, sig := parts[0], parts[1]
-		if !validateCookie(userID, sig, secretKey) {
+		userID, err := uuid.Parse(claims.Subject)
+		if err != nil {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		c.Set("user_id", userID)
+		c.Set("user_id", claims.Subject)
+		c.Set("session_id", claims.SessionID)
+		setActor(c, userID)
 		c.Next()
 	}
 }
+
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyChecker, key string) {
+	apiKey, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), key)
+	if errors.Is(err, customerrors.ErrInvalidAPIKey) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("api key check failed: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Set("user_id", apiKey.UserID.String())
+	c.Set(apiKeyScopesKey, apiKey.Scopes)
+	setActor(c, apiKey.UserID)
+	c.Next()
+}
+
+// setActor запоминает пользователя в контексте запроса: от его имени пишутся события журнала аудита.
+func setActor(c *gin.Context, userID uuid.UUID) {
+	c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), userID))
+}
+
+// RequireScope пропускает запросы с access-токеном, а запросы по API-ключу — только если
+// ключу выдана область scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scopes, ok := c.Get(apiKeyScopesKey); ok && !hasScope(scopes.([]string), scope) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionOnly закрывает для API-ключей эндпоинты управления учётной записью.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(apiKeyScopesKey); ok {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
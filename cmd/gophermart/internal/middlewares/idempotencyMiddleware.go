@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+const (
+	idempotencyHeader     = "Idempotency-Key"
+	idempotencyMaxKeyLen  = 255
+	idempotencyReplayFlag = "Idempotent-Replayed"
+)
+
+type IdempotencyStore interface {
+	BeginIdempotentRequest(ctx context.Context, userID, key, fingerprint string) (*models.IdempotencyRecord, error)
+	CompleteIdempotentRequest(ctx context.Context, userID, key string, statusCode int, body []byte) error
+	ReleaseIdempotentRequest(ctx context.Context, userID, key string) error
+}
+
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// IdempotencyMiddleware повторяет сохранённый ответ на запрос с уже использованным
+// Idempotency-Key. Тот же ключ с другим телом — 422, пока первый запрос не завершён — 409.
+// Ответы 5xx не сохраняются, такой запрос можно повторить с тем же ключом. Запрос, оставшийся
+// незавершённым (например, процесс упал), через минуту можно повторить с тем же ключом и телом.
+// Должен стоять после AuthMiddleware: ключи хранятся отдельно для каждого пользователя.
+func IdempotencyMiddleware(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotencyMaxKeyLen {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.FullPath()+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		ctx := c.Request.Context()
+		rec, err := store.BeginIdempotentRequest(ctx, userID, key, fingerprint)
+		if err != nil {
+			log.Printf("idempotency lookup failed: %v", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if rec != nil {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				c.AbortWithStatus(http.StatusUnprocessableEntity)
+			case rec.StatusCode == 0:
+				c.AbortWithStatus(http.StatusConflict)
+			default:
+				c.Header(idempotencyReplayFlag, "true")
+				if len(rec.Body) == 0 {
+					c.AbortWithStatus(rec.StatusCode)
+				} else {
+					c.Data(rec.StatusCode, http.DetectContentType(rec.Body), rec.Body)
+					c.Abort()
+				}
+			}
+			return
+		}
+
+		w := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		// запрос мог быть отменён клиентом, результат всё равно нужно сохранить
+		saveCtx := context.WithoutCancel(ctx)
+		status := w.Status()
+		if retryableStatus(status) {
+			err = store.ReleaseIdempotentRequest(saveCtx, userID, key)
+		} else {
+			err = store.CompleteIdempotentRequest(saveCtx, userID, key, status, w.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+// retryableStatus — ответы, которые зависят не от самой операции, а от состояния сервера
+// или предъявленных учётных данных: с тем же ключом запрос можно повторить.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= http.StatusInternalServerError
+}
@@ -39,6 +39,7 @@ func GinLoggingMiddleware() gin.HandlerFunc {
 			"size", respData.size,
 			"client_ip", c.ClientIP(),
 			"user_agent", c.Request.UserAgent(),
+			"request_id", c.GetString(requestIDKey),
 		)
 	}
 }
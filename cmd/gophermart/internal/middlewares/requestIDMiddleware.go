@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+
+	maxRequestIDLength = 64
+)
+
+// RequestIDMiddleware берёт идентификатор запроса из X-Request-ID или выдаёт новый, возвращает его
+// в ответе и кладёт вместе с IP клиента в контекст: оттуда их берут записи журнала аудита.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(audit.WithRequest(c.Request.Context(), id, c.ClientIP()))
+		c.Next()
+	}
+}
+
+// validRequestID не пускает в журнал произвольные строки от клиента.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
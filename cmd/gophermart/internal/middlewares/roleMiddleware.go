@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+const roleKey = "role"
+
+// RoleChecker возвращает текущую роль пользователя; заблокированному — ErrUserBlocked.
+type RoleChecker interface {
+	UserRole(ctx context.Context, userID string) (string, error)
+}
+
+// RequireRole пропускает только пользователей с одной из ролей allowed. Роль читается из хранилища
+// на каждый запрос, поэтому её смена действует сразу; вложенные проверки переиспользуют уже прочитанную.
+func RequireRole(roles RoleChecker, allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(roleKey)
+		if role == "" {
+			var err error
+			role, err = roles.UserRole(c.Request.Context(), c.GetString("user_id"))
+			if errors.Is(err, customerrors.ErrUserBlocked) || errors.Is(err, customerrors.ErrUserNotFound) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				log.Printf("role check failed: %v", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			c.Set(roleKey, role)
+		}
+
+		for _, r := range allowed {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы ручной корректировки баланса: предложенная корректировка применяется
+// только после одобрения вторым администратором.
+const (
+	AdjustmentPending  = "pending"
+	AdjustmentApproved = "approved"
+	AdjustmentRejected = "rejected"
+)
+
+const (
+	AdjustmentCredit = "credit"
+	AdjustmentDebit  = "debit"
+)
+
+// Коды причин корректировки; для AdjustmentReasonOther обязателен комментарий.
+const (
+	AdjustmentReasonGoodwill          = "goodwill"
+	AdjustmentReasonMissingAccrual    = "missing_accrual"
+	AdjustmentReasonAccrualCorrection = "accrual_correction"
+	AdjustmentReasonFraud             = "fraud"
+	AdjustmentReasonOther             = "other"
+)
+
+var AdjustmentReasons = []string{
+	AdjustmentReasonGoodwill,
+	AdjustmentReasonMissingAccrual,
+	AdjustmentReasonAccrualCorrection,
+	AdjustmentReasonFraud,
+	AdjustmentReasonOther,
+}
+
+// Adjustment — ручная корректировка: положительная сумма зачисляется, отрицательная списывается.
+type Adjustment struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Amount     Points     `json:"amount"`
+	Reason     string     `json:"reason"`
+	Comment    string     `json:"comment,omitempty"`
+	Status     string     `json:"status"`
+	ProposedBy uuid.UUID  `json:"proposed_by"`
+	ProposedAt time.Time  `json:"proposed_at"`
+	DecidedBy  *uuid.UUID `json:"decided_by,omitempty"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+}
+
+type AdjustmentRequest struct {
+	Type    string `json:"type" binding:"required"`
+	Amount  Points `json:"amount" binding:"required"`
+	Reason  string `json:"reason" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// AdjustmentFilter: пустые поля не ограничивают выборку; список идёт от новых к старым.
+type AdjustmentFilter struct {
+	Status string
+	UserID *uuid.UUID
+	Limit  int
+}
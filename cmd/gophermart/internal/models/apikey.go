@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Области действия API-ключей.
+const (
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+	ScopeBalanceRead = "balance:read"
+	ScopeWithdraw    = "withdraw"
+)
+
+var APIKeyScopes = []string{ScopeOrdersRead, ScopeOrdersWrite, ScopeBalanceRead, ScopeWithdraw}
+
+// APIKey — ключ для доступа интеграций к эндпоинтам пользователя; сам ключ хранится только в виде хеша.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// NewAPIKey — только что созданный ключ; Key показывается владельцу один раз.
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Действия, записываемые в журнал аудита.
+const (
+	AuditUserRegistered     = "user.registered"
+	AuditLogin              = "auth.login"
+	AuditAuthLockout        = "auth.lockout"
+	AuditSessionRevoked     = "auth.session_revoked"
+	AuditSessionsRevoked    = "auth.sessions_revoked"
+	AuditPasswordChanged    = "auth.password_changed"
+	AuditPasswordReset      = "auth.password_reset"
+	AuditTOTPEnabled        = "auth.2fa_enabled"
+	AuditAPIKeyCreated      = "auth.api_key_created"
+	AuditAPIKeyRevoked      = "auth.api_key_revoked"
+	AuditOrderUploaded      = "order.uploaded"
+	AuditUserBlocked        = "admin.user_blocked"
+	AuditUserUnblocked      = "admin.user_unblocked"
+	AuditUserRoleChanged    = "admin.role_changed"
+	AuditAccrualRetriggered = "admin.accrual_retriggered"
+	AuditAdjustmentProposed = "admin.adjustment_proposed"
+	AuditAdjustmentApproved = "admin.adjustment_approved"
+	AuditAdjustmentRejected = "admin.adjustment_rejected"
+)
+
+// AuditMoneyPrefix — префикс действий, которые пишутся вместе с каждой проводкой по балансу:
+// money.accrual, money.withdrawal, money.adjustment, money.expiry.
+const AuditMoneyPrefix = "money."
+
+// AuditEvent — неизменяемая запись журнала аудита. ActorID — кто выполнил действие
+// (пусто для фоновых задач и анонимных запросов), UserID — чей аккаунт или баланс затронут,
+// Target — объект действия: номер заказа, идентификатор сессии, ключа или корректировки.
+// Hash связывает запись с предыдущей (PrevHash), поэтому удаление или правка записи
+// обнаруживаются при проверке цепочки; пока запись не запечатана, оба хеша пусты.
+type AuditEvent struct {
+	ID            int64                  `json:"id"`
+	Action        string                 `json:"action"`
+	ActorID       *uuid.UUID             `json:"actor_id,omitempty"`
+	UserID        *uuid.UUID             `json:"user_id,omitempty"`
+	Target        string                 `json:"target,omitempty"`
+	IP            string                 `json:"ip,omitempty"`
+	RequestID     string                 `json:"request_id,omitempty"`
+	BalanceBefore *Points                `json:"balance_before,omitempty"`
+	BalanceAfter  *Points                `json:"balance_after,omitempty"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	PrevHash      string                 `json:"prev_hash,omitempty"`
+	Hash          string                 `json:"hash,omitempty"`
+}
+
+// AuditChainHead — последняя запечатанная запись цепочки; Seq — число записей в цепочке.
+type AuditChainHead struct {
+	Seq     int64
+	EventID int64
+	Hash    string
+}
+
+// AuditFilter — условия выборки журнала; выдача идёт от новых записей к старым,
+// ключ курсора — идентификатор записи.
+type AuditFilter struct {
+	Action    string
+	ActorID   *uuid.UUID
+	UserID    *uuid.UUID
+	Target    string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+	Cursor    *Cursor
+	Limit     int
+}
+
+// AuditVerification — результат проверки цепочки хешей. BrokenAt — первая запись,
+// на которой цепочка не сходится.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	LastID   int64  `json:"last_id,omitempty"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor — позиция keyset-пагинации: время записи и уникальный ключ для разрешения равенств.
+type Cursor struct {
+	At  time.Time `json:"at"`
+	Key string    `json:"key"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Key == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ListFilter — общие параметры постраничной выдачи списков. Limit == 0 означает «без ограничения».
+type ListFilter struct {
+	Statuses []string
+	From     *time.Time
+	To       *time.Time
+	Cursor   *Cursor
+	Limit    int
+	Desc     bool
+}
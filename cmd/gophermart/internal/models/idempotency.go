@@ -0,0 +1,9 @@
+package models
+
+// IdempotencyRecord — сохранённый результат запроса с заголовком Idempotency-Key.
+// StatusCode == 0 означает, что первый запрос с этим ключом ещё выполняется.
+type IdempotencyRecord struct {
+	Fingerprint string
+	StatusCode  int
+	Body        []byte
+}
@@ -0,0 +1,50 @@
+package models
+
+import "github.com/google/uuid"
+
+// Типы проводок в журнале баллов.
+const (
+	LedgerEntryAccrual    = "accrual"
+	LedgerEntryWithdrawal = "withdrawal"
+	LedgerEntryReversal   = "reversal"
+	LedgerEntryAdjustment = "adjustment"
+	LedgerEntryExpiry     = "expiry"
+)
+
+// Счета журнала. Баланс пользователя — сумма по счёту LedgerAccountUser,
+// списано всего — сумма по LedgerAccountWithdrawals; остальные счета — источники начислений.
+const (
+	LedgerAccountUser        = "user"
+	LedgerAccountAccrual     = "accrual"
+	LedgerAccountWithdrawals = "withdrawals"
+	LedgerAccountAdjustments = "adjustments"
+	LedgerAccountExpired     = "expired"
+)
+
+// LedgerPosting — одна операция журнала: Amount зачисляется на счёт пользователя
+// (отрицательная сумма — списание) и с обратным знаком проводится по CounterAccount.
+// Зачисление создаёт партию баллов, которая сгорает через ExpiryMonths месяцев (0 — бессрочно);
+// списания гасят партии в порядке поступления.
+type LedgerPosting struct {
+	UserID         uuid.UUID
+	EntryType      string
+	Amount         Points
+	CounterAccount string
+	OrderNumber    string
+	WithdrawalID   *int
+	AdjustmentID   *uuid.UUID
+	ExpiryMonths   int
+}
+
+type BalanceDrift struct {
+	UserID          uuid.UUID `json:"user_id"`
+	CachedBalance   Points    `json:"cached_balance"`
+	LedgerBalance   Points    `json:"ledger_balance"`
+	CachedWithdrawn Points    `json:"cached_withdrawn"`
+	LedgerWithdrawn Points    `json:"ledger_withdrawn"`
+}
+
+type ReconciliationReport struct {
+	Drifts          []BalanceDrift `json:"drifts"`
+	UnbalancedTxIDs []uuid.UUID    `json:"unbalanced_tx_ids"`
+}
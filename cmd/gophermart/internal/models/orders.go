@@ -1,16 +1,42 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// Статусы заказа в gophermart.
+const (
+	OrderStatusNew        = "NEW"
+	OrderStatusProcessing = "PROCESSING"
+	OrderStatusInvalid    = "INVALID"
+	OrderStatusProcessed  = "PROCESSED"
+)
+
+// Статусы расчёта в системе начислений.
+const (
+	AccrualStatusRegistered = "REGISTERED"
+	AccrualStatusProcessing = "PROCESSING"
+	AccrualStatusInvalid    = "INVALID"
+	AccrualStatusProcessed  = "PROCESSED"
+)
 
 type Order struct {
 	Number     string    `json:"number"`
 	Status     string    `json:"status"`
-	Accrual    *float64  `json:"accrual,omitempty"`
+	Accrual    *Points   `json:"accrual,omitempty"`
 	UploadedAt time.Time `json:"uploaded_at"`
 }
 
+// AccrualResponse.Accrual остаётся json.Number: система начислений может прислать больше двух
+// знаков после точки, такие значения округляются через RoundPoints.
 type AccrualResponse struct {
-	Order   string   `json:"order"`
-	Status  string   `json:"status"`
-	Accrual *float64 `json:"accrual,omitempty"`
+	Order   string       `json:"order"`
+	Status  string       `json:"status"`
+	Accrual *json.Number `json:"accrual,omitempty"`
+}
+
+type AccrualJob struct {
+	OrderNumber string
+	Attempts    int
 }
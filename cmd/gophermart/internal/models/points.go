@@ -0,0 +1,180 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgtype"
+)
+
+// Points — количество баллов в сотых долях, так суммы не накапливают ошибку округления.
+// В JSON и в NUMERIC(18, 2) представляется десятичным числом с двумя знаками после точки.
+type Points int64
+
+const pointsScale = 100
+
+var ErrInvalidPoints = errors.New("invalid points amount")
+
+// ParsePoints разбирает десятичное число и отклоняет значения точнее сотых.
+func ParsePoints(s string) (Points, error) {
+	return parsePoints(s, false)
+}
+
+// RoundPoints разбирает десятичное число, округляя его до сотых (половина — от нуля).
+func RoundPoints(s string) (Points, error) {
+	return parsePoints(s, true)
+}
+
+func PointsFromFloat(f float64) Points {
+	return Points(math.Round(f * pointsScale))
+}
+
+func (p Points) IsPositive() bool {
+	return p > 0
+}
+
+func (p Points) String() string {
+	sign := ""
+	v := int64(p)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole, frac := v/pointsScale, v%pointsScale
+	switch {
+	case frac == 0:
+		return fmt.Sprintf("%s%d", sign, whole)
+	case frac%10 == 0:
+		return fmt.Sprintf("%s%d.%d", sign, whole, frac/10)
+	default:
+		return fmt.Sprintf("%s%d.%02d", sign, whole, frac)
+	}
+}
+
+func (p Points) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Points) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		return fmt.Errorf("%w: must be a JSON number", ErrInvalidPoints)
+	}
+	v, err := ParsePoints(s)
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// Scan получает NUMERIC от pgx в виде строки, в том числе в форме "72998e-2".
+func (p *Points) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = 0
+		return nil
+	case string:
+		parsed, err := RoundPoints(v)
+		if err != nil {
+			return err
+		}
+		*p = parsed
+		return nil
+	case []byte:
+		return p.Scan(string(v))
+	case int64:
+		*p = Points(v * pointsScale)
+		return nil
+	case float64:
+		*p = PointsFromFloat(v)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Points", src)
+	}
+}
+
+func (p Points) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// EncodeText нужен pgx: без него Points как int64 ушёл бы в NUMERIC без учёта масштаба.
+func (p Points) EncodeText(_ *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return append(buf, p.String()...), nil
+}
+
+func parsePoints(s string, round bool) (Points, error) {
+	invalid := fmt.Errorf("%w: %q", ErrInvalidPoints, s)
+
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return 0, invalid
+		}
+		mantissa, exp = s[:i], e
+	}
+
+	negative := false
+	switch {
+	case strings.HasPrefix(mantissa, "-"):
+		negative = true
+		mantissa = mantissa[1:]
+	case strings.HasPrefix(mantissa, "+"):
+		mantissa = mantissa[1:]
+	}
+
+	whole, frac, _ := strings.Cut(mantissa, ".")
+	if whole == "" && frac == "" {
+		return 0, invalid
+	}
+	digits := whole + frac
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, invalid
+		}
+	}
+
+	// value = digits * 10^(exp - len(frac)); приводим к сотым
+	n, ok := new(big.Int).SetString("0"+digits, 10)
+	if !ok {
+		return 0, invalid
+	}
+	shift := exp - len(frac) + 2
+	if shift > 20 || shift < -40 {
+		return 0, invalid
+	}
+	ten := big.NewInt(10)
+	if shift >= 0 {
+		n.Mul(n, new(big.Int).Exp(ten, big.NewInt(int64(shift)), nil))
+	} else {
+		div := new(big.Int).Exp(ten, big.NewInt(int64(-shift)), nil)
+		q, r := new(big.Int).QuoRem(n, div, new(big.Int))
+		if r.Sign() != 0 {
+			if !round {
+				return 0, fmt.Errorf("%w: more than two decimal places in %q", ErrInvalidPoints, s)
+			}
+			if r.Mul(r, big.NewInt(2)).Cmp(div) >= 0 {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+		n = q
+	}
+
+	if !n.IsInt64() {
+		return 0, invalid
+	}
+	v := n.Int64()
+	if negative {
+		v = -v
+	}
+	return Points(v), nil
+}
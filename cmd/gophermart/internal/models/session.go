@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionInfo описывает устройство, с которого выполнен вход.
+type SessionInfo struct {
+	UserID    uuid.UUID
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Device     string    `json:"device,omitempty"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
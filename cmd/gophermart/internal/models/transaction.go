@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	TransactionAccrual    = "accrual"
+	TransactionWithdrawal = "withdrawal"
+	TransactionAdjustment = "adjustment"
+	TransactionExpiry     = "expiry"
+)
+
+// Transaction — запись ленты операций: начисления положительны, списания отрицательны,
+// Balance — баланс сразу после операции. У ручных корректировок нет заказа, вместо него — код причины.
+type Transaction struct {
+	Type       string    `json:"type"`
+	Order      string    `json:"order,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Amount     Points    `json:"amount"`
+	Balance    Points    `json:"balance"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Key        string    `json:"-"`
+}
+
+// TransactionFilter: From включительно, To исключительно; лента идёт от новых к старым.
+type TransactionFilter struct {
+	Types  []string
+	From   *time.Time
+	To     *time.Time
+	Cursor *Cursor
+	Limit  int
+}
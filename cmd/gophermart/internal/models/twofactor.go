@@ -0,0 +1,38 @@
+package models
+
+import "github.com/google/uuid"
+
+// TOTP — второй фактор пользователя. LastStep — номер последнего принятого интервала,
+// коды этого и более ранних интервалов повторно не принимаются.
+type TOTP struct {
+	Secret    string
+	Confirmed bool
+	LastStep  int64
+}
+
+// LoginChallenge — вход, ожидающий кода второго фактора после успешной проверки пароля.
+type LoginChallenge struct {
+	UserID uuid.UUID
+	Login  string
+	Device string
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type OTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// LoginChallengeResponse возвращается вместо токенов, если у пользователя включён второй фактор.
+type LoginChallengeResponse struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"`
+}
+
+type LoginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
@@ -2,15 +2,36 @@ package models
 
 import "github.com/google/uuid"
 
+// Роли пользователей: support видит чужие данные, admin также управляет учётными записями.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
 type User struct {
 	ID           uuid.UUID
 	Login        string
 	PasswordHash string
-	Balance      int
-	Withdrawn    int
+	Role         string
+	Blocked      bool
+	Balance      Points
+	Withdrawn    Points
 }
 
 type AuthRequest struct {
 	Login    string `json:"login" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+// UserInfo — карточка пользователя в административном API.
+type UserInfo struct {
+	ID      uuid.UUID `json:"id"`
+	Login   string    `json:"login"`
+	Role    string    `json:"role"`
+	Blocked bool      `json:"blocked"`
+}
+
+type RoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -3,17 +3,26 @@ package models
 import "time"
 
 type Withdrawal struct {
+	ID          int       `json:"-"`
 	Order       string    `json:"order"`
-	Sum         float64   `json:"sum"`
+	Sum         Points    `json:"sum"`
 	ProcessedAt time.Time `json:"processed_at"`
 }
 
 type WithdrawalRequest struct {
-	Order string  `json:"order" binding:"required"`
-	Sum   float64 `json:"sum" binding:"required"`
+	Order string `json:"order" binding:"required"`
+	Sum   Points `json:"sum" binding:"required"`
 }
 
 type Balance struct {
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Current   Points `json:"current"`
+	Withdrawn Points `json:"withdrawn"`
+	// UpcomingExpirations — ближайшие даты сгорания баллов с суммой, сгорающей в каждую из них.
+	UpcomingExpirations []PointsExpiration `json:"upcoming_expirations,omitempty"`
+}
+
+// PointsExpiration — баллы, которые сгорят в течение дня Date (UTC).
+type PointsExpiration struct {
+	Date   string `json:"date"`
+	Amount Points `json:"amount"`
 }
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, notice models.PasswordResetNotice) error
+}
+
+// LogNotifier пишет токены сброса в лог сервера; годится только для локального запуска.
+type LogNotifier struct{}
+
+func (LogNotifier) SendPasswordReset(_ context.Context, notice models.PasswordResetNotice) error {
+	log.Printf("password reset for %s: token %s, expires at %s",
+		notice.Login, notice.Token, notice.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"))
+	return nil
+}
+
+// FileNotifier дописывает сообщения в файл по одному JSON на строку.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (f *FileNotifier) SendPasswordReset(_ context.Context, notice models.PasswordResetNotice) error {
+	line, err := json.Marshal(struct {
+		Type string `json:"type"`
+		models.PasswordResetNotice
+	}{Type: "password_reset", PasswordResetNotice: notice})
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// New создаёт notifier по описанию: "log" или "file:<path>".
+func New(spec string) (Notifier, error) {
+	switch {
+	case spec == "log":
+		return LogNotifier{}, nil
+	case strings.HasPrefix(spec, "file:") && len(spec) > len("file:"):
+		return NewFileNotifier(strings.TrimPrefix(spec, "file:")), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q, expected log or file:<path>", spec)
+	}
+}
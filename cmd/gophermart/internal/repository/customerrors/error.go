@@ -6,3 +6,33 @@ var ErrOrderUploadedByAnotherUser = errors.New("order uploaded by another user")
 var ErrOrderAlreadyUploadedBySameUser = errors.New("order uploaded by same user")
 var ErrInsufficientBalance = errors.New("insufficient balance")
 var ErrInvalidOrderNumber = errors.New("invalid order number")
+var ErrIllegalOrderTransition = errors.New("illegal order status transition")
+var ErrOrderStatusChanged = errors.New("order status changed concurrently")
+var ErrLoginAlreadyExists = errors.New("login already exists")
+var ErrUserNotFound = errors.New("user not found")
+var ErrOrderNotFound = errors.New("order not found")
+var ErrInvalidAmount = errors.New("amount must be positive")
+var ErrWithdrawalOrderExists = errors.New("withdrawal for this order already exists")
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+var ErrSessionNotFound = errors.New("session not found")
+var ErrInvalidCredentials = errors.New("invalid login or password")
+var ErrWeakPassword = errors.New("password does not satisfy policy")
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+var ErrTOTPNotEnrolled = errors.New("two-factor authentication is not enrolled")
+var ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+var ErrInvalidOTP = errors.New("invalid one-time code")
+var ErrTwoFactorRequired = errors.New("two-factor code required")
+var ErrInvalidLoginChallenge = errors.New("invalid or expired login challenge")
+var ErrInvalidAPIKey = errors.New("invalid api key")
+var ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+var ErrAPIKeyNotFound = errors.New("api key not found")
+var ErrUserBlocked = errors.New("user is blocked")
+var ErrInvalidRole = errors.New("invalid role")
+var ErrSelfModification = errors.New("cannot change own role or block own account")
+var ErrOrderAlreadyFinal = errors.New("order already has final status")
+var ErrInvalidAdjustment = errors.New("invalid adjustment")
+var ErrAdjustmentNotFound = errors.New("adjustment not found")
+var ErrAdjustmentNotPending = errors.New("adjustment already decided")
+var ErrSelfApproval = errors.New("adjustment must be approved by another admin")
+var ErrPointLotsShortfall = errors.New("point lots do not cover the debit")
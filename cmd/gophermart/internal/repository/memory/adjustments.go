@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (m *MemoryStore) CreateAdjustment(ctx context.Context, adj models.Adjustment) (*models.Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[adj.UserID]; !ok {
+		return nil, customerrors.ErrUserNotFound
+	}
+	adj.Status = models.AdjustmentPending
+	adj.ProposedAt = time.Now()
+	adj.DecidedBy, adj.DecidedAt = nil, nil
+	m.adjustments = append(m.adjustments, &adj)
+	m.appendAudit(ctx, audit.AdjustmentEvent(models.AuditAdjustmentProposed, &adj))
+	res := adj
+	return &res, nil
+}
+
+func (m *MemoryStore) ListAdjustments(_ context.Context, filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []models.Adjustment
+	// adjustments хранятся в порядке создания, список отдаётся от новых к старым
+	for i := len(m.adjustments) - 1; i >= 0 && len(list) < filter.Limit; i-- {
+		a := m.adjustments[i]
+		if filter.Status != "" && a.Status != filter.Status {
+			continue
+		}
+		if filter.UserID != nil && a.UserID != *filter.UserID {
+			continue
+		}
+		list = append(list, *a)
+	}
+	return list, nil
+}
+
+func (m *MemoryStore) ApproveAdjustment(ctx context.Context, adjustmentID, approverID uuid.UUID, expiryMonths int) (*models.Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	adj, err := m.pendingAdjustment(adjustmentID)
+	if err != nil {
+		return nil, err
+	}
+	if adj.ProposedBy == approverID {
+		return nil, customerrors.ErrSelfApproval
+	}
+	if adj.Amount < 0 {
+		if _, err := m.expirePointLots(ctx, adj.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if m.users[adj.UserID].balance+adj.Amount < 0 {
+		return nil, customerrors.ErrInsufficientBalance
+	}
+
+	err = m.postLedger(ctx, models.LedgerPosting{
+		UserID:         adj.UserID,
+		EntryType:      models.LedgerEntryAdjustment,
+		Amount:         adj.Amount,
+		CounterAccount: models.LedgerAccountAdjustments,
+		AdjustmentID:   &adj.ID,
+		ExpiryMonths:   expiryMonths,
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.decideAdjustment(adj, models.AdjustmentApproved, approverID)
+	m.appendAudit(ctx, audit.AdjustmentEvent(models.AuditAdjustmentApproved, adj))
+	res := *adj
+	return &res, nil
+}
+
+func (m *MemoryStore) RejectAdjustment(ctx context.Context, adjustmentID, deciderID uuid.UUID) (*models.Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	adj, err := m.pendingAdjustment(adjustmentID)
+	if err != nil {
+		return nil, err
+	}
+	m.decideAdjustment(adj, models.AdjustmentRejected, deciderID)
+	m.appendAudit(ctx, audit.AdjustmentEvent(models.AuditAdjustmentRejected, adj))
+	res := *adj
+	return &res, nil
+}
+
+// pendingAdjustment и decideAdjustment вызываются под m.mu.
+func (m *MemoryStore) pendingAdjustment(adjustmentID uuid.UUID) (*models.Adjustment, error) {
+	for _, a := range m.adjustments {
+		if a.ID == adjustmentID {
+			if a.Status != models.AdjustmentPending {
+				return nil, customerrors.ErrAdjustmentNotPending
+			}
+			return a, nil
+		}
+	}
+	return nil, customerrors.ErrAdjustmentNotFound
+}
+
+func (m *MemoryStore) decideAdjustment(adj *models.Adjustment, status string, deciderID uuid.UUID) {
+	now := time.Now()
+	adj.Status = status
+	adj.DecidedBy, adj.DecidedAt = &deciderID, &now
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (m *MemoryStore) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return customerrors.ErrUserNotFound
+	}
+	previous := u.Role
+	u.Role = role
+	m.appendAudit(ctx, models.AuditEvent{
+		Action:  models.AuditUserRoleChanged,
+		UserID:  &userID,
+		Details: map[string]interface{}{"from": previous, "to": role},
+	})
+	return nil
+}
+
+func (m *MemoryStore) SetUserBlocked(ctx context.Context, userID uuid.UUID, blocked bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return customerrors.ErrUserNotFound
+	}
+	u.Blocked = blocked
+	action := models.AuditUserUnblocked
+	if blocked {
+		action = models.AuditUserBlocked
+	}
+	m.appendAudit(ctx, models.AuditEvent{Action: action, UserID: &userID})
+	return nil
+}
+
+func (m *MemoryStore) RetriggerAccrualJob(ctx context.Context, orderNumber string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[orderNumber]
+	if !ok {
+		return customerrors.ErrOrderNotFound
+	}
+	job, ok := m.jobs[orderNumber]
+	if !ok {
+		job = &accrualJob{}
+		m.jobs[orderNumber] = job
+	}
+	job.nextRunAt, job.attempts, job.lastErr = time.Now(), 0, ""
+	m.appendAudit(ctx, models.AuditEvent{Action: models.AuditAccrualRetriggered, UserID: &o.userID, Target: orderNumber})
+	return nil
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (m *MemoryStore) CreateAPIKey(ctx context.Context, key models.APIKey, hash string) (*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key.CreatedAt = time.Now()
+	key.Scopes = append([]string(nil), key.Scopes...)
+	m.apiKeys[hash] = &apiKey{APIKey: key}
+	m.appendAudit(ctx, audit.APIKeyCreatedEvent(key))
+	return copyAPIKey(key), nil
+}
+
+func (m *MemoryStore) ListAPIKeys(_ context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []models.APIKey
+	for _, k := range m.apiKeys {
+		if k.UserID == userID && !k.revoked {
+			keys = append(keys, *copyAPIKey(k.APIKey))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (m *MemoryStore) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.apiKeys {
+		if k.ID == keyID && k.UserID == userID && !k.revoked {
+			k.revoked = true
+			m.appendAudit(ctx, models.AuditEvent{Action: models.AuditAPIKeyRevoked, UserID: &userID, Target: keyID.String()})
+			return nil
+		}
+	}
+	return customerrors.ErrAPIKeyNotFound
+}
+
+func (m *MemoryStore) UseAPIKey(_ context.Context, hash string) (*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.apiKeys[hash]
+	if !ok || k.revoked || m.users[k.UserID].Blocked {
+		return nil, customerrors.ErrInvalidAPIKey
+	}
+	now := time.Now()
+	k.LastUsedAt = &now
+	return copyAPIKey(k.APIKey), nil
+}
+
+func copyAPIKey(k models.APIKey) *models.APIKey {
+	k.Scopes = append([]string(nil), k.Scopes...)
+	if k.LastUsedAt != nil {
+		t := *k.LastUsedAt
+		k.LastUsedAt = &t
+	}
+	return &k
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type attempts struct {
+	failures     int
+	windowStart  time.Time
+	blockedUntil time.Time
+}
+
+// AttemptStore хранит счётчики неудачных входов в памяти процесса; у каждой реплики они свои.
+type AttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]*attempts
+	lastSweep time.Time
+}
+
+func NewAttemptStore() *AttemptStore {
+	return &AttemptStore{entries: make(map[string]*attempts)}
+}
+
+func (a *AttemptStore) BlockedFor(_ context.Context, key string) (time.Duration, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e, ok := a.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	if d := time.Until(e.blockedUntil); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
+
+func (a *AttemptStore) RecordFailure(_ context.Context, key string, window time.Duration) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	a.sweep(now, window)
+
+	e, ok := a.entries[key]
+	if !ok {
+		e = &attempts{windowStart: now}
+		a.entries[key] = e
+	}
+	if now.Sub(e.windowStart) > window {
+		e.failures, e.windowStart = 0, now
+	}
+	e.failures++
+	return e.failures, nil
+}
+
+func (a *AttemptStore) Block(_ context.Context, key string, d time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if e, ok := a.entries[key]; ok {
+		if until := time.Now().Add(d); until.After(e.blockedUntil) {
+			e.blockedUntil = until
+		}
+	}
+	return nil
+}
+
+func (a *AttemptStore) Reset(_ context.Context, key string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.entries, key)
+	return nil
+}
+
+// sweep раз в окно удаляет записи с истёкшим окном и блокировкой.
+func (a *AttemptStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(a.lastSweep) < window {
+		return
+	}
+	for k, e := range a.entries {
+		if now.Sub(e.windowStart) > window && now.After(e.blockedUntil) {
+			delete(a.entries, k)
+		}
+	}
+	a.lastSweep = now
+}
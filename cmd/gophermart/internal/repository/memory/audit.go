@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+// appendAudit вызывается под m.mu, вместе с изменением, которое описывает запись. В памяти
+// запись запечатывается сразу, и номер в цепочке совпадает с идентификатором.
+func (m *MemoryStore) appendAudit(ctx context.Context, e models.AuditEvent) {
+	e = audit.NewEvent(ctx, e)
+	e.ID = int64(len(m.audit)) + 1
+	prevHash := ""
+	if len(m.audit) > 0 {
+		prevHash = m.audit[len(m.audit)-1].Hash
+	}
+	audit.Seal(&e, prevHash)
+	m.audit = append(m.audit, e)
+}
+
+func (m *MemoryStore) WriteAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.appendAudit(ctx, event)
+	return nil
+}
+
+func (m *MemoryStore) ListAuditEvents(_ context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var beforeID int64
+	if filter.Cursor != nil {
+		id, err := strconv.ParseInt(filter.Cursor.Key, 10, 64)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		beforeID = id
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var res []models.AuditEvent
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		e := m.audit[i]
+		if filter.Cursor != nil && e.ID >= beforeID {
+			continue
+		}
+		if !matchAudit(e, filter) {
+			continue
+		}
+		res = append(res, e)
+		if filter.Limit > 0 && len(res) == filter.Limit {
+			break
+		}
+	}
+	return res, nil
+}
+
+func matchAudit(e models.AuditEvent, filter models.AuditFilter) bool {
+	switch {
+	case filter.Action != "" && e.Action != filter.Action:
+		return false
+	case filter.ActorID != nil && (e.ActorID == nil || *e.ActorID != *filter.ActorID):
+		return false
+	case filter.UserID != nil && (e.UserID == nil || *e.UserID != *filter.UserID):
+		return false
+	case filter.Target != "" && e.Target != filter.Target:
+		return false
+	case filter.RequestID != "" && e.RequestID != filter.RequestID:
+		return false
+	case filter.From != nil && e.CreatedAt.Before(*filter.From):
+		return false
+	case filter.To != nil && !e.CreatedAt.Before(*filter.To):
+		return false
+	}
+	return true
+}
+
+// SealAuditChain ничего не делает: записи запечатывает appendAudit.
+func (m *MemoryStore) SealAuditChain(context.Context, int) (int, error) {
+	return 0, nil
+}
+
+func (m *MemoryStore) GetAuditChain(_ context.Context, afterSeq int64, limit int) ([]models.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if afterSeq < 0 || afterSeq >= int64(len(m.audit)) {
+		return nil, nil
+	}
+	tail := m.audit[afterSeq:]
+	if limit > 0 && len(tail) > limit {
+		tail = tail[:limit]
+	}
+	return append([]models.AuditEvent(nil), tail...), nil
+}
+
+func (m *MemoryStore) GetAuditChainHead(_ context.Context) (models.AuditChainHead, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.audit) == 0 {
+		return models.AuditChainHead{}, nil
+	}
+	last := m.audit[len(m.audit)-1]
+	return models.AuditChainHead{Seq: int64(len(m.audit)), EventID: last.ID, Hash: last.Hash}, nil
+}
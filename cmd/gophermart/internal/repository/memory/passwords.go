@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (m *MemoryStore) GetUserByID(_ context.Context, userID uuid.UUID) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return nil, customerrors.ErrUserNotFound
+	}
+	res := u.User
+	return &res, nil
+}
+
+func (m *MemoryStore) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return customerrors.ErrUserNotFound
+	}
+	u.PasswordHash = passwordHash
+	m.appendAudit(ctx, models.AuditEvent{Action: models.AuditPasswordChanged, UserID: &userID})
+	return nil
+}
+
+func (m *MemoryStore) RehashPassword(_ context.Context, userID uuid.UUID, oldHash, newHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.users[userID]; ok && u.PasswordHash == oldHash {
+		u.PasswordHash = newHash
+	}
+	return nil
+}
+
+func (m *MemoryStore) CreatePasswordReset(_ context.Context, userID uuid.UUID, hash string, ttl time.Duration) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.resets {
+		if r.userID == userID {
+			r.used = true
+		}
+	}
+	expiresAt := time.Now().Add(ttl)
+	m.resets[hash] = &passwordReset{userID: userID, expiresAt: expiresAt}
+	return expiresAt, nil
+}
+
+func (m *MemoryStore) ConsumePasswordReset(ctx context.Context, hash, passwordHash string) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.resets[hash]
+	if !ok || r.used || !time.Now().Before(r.expiresAt) {
+		return uuid.Nil, customerrors.ErrInvalidResetToken
+	}
+	u, ok := m.users[r.userID]
+	if !ok {
+		return uuid.Nil, customerrors.ErrInvalidResetToken
+	}
+	r.used = true
+	u.PasswordHash = passwordHash
+	m.appendAudit(ctx, models.AuditEvent{Action: models.AuditPasswordReset, UserID: &r.userID})
+	return r.userID, nil
+}
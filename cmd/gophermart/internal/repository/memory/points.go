@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// pointLot — партия баллов от одного зачисления; expiresAt == nil — бессрочная.
+type pointLot struct {
+	userID    uuid.UUID
+	amount    models.Points
+	remaining models.Points
+	expiresAt *time.Time
+}
+
+func (m *MemoryStore) addPointLot(p models.LedgerPosting, now time.Time) {
+	lot := &pointLot{userID: p.UserID, amount: p.Amount, remaining: p.Amount}
+	if p.ExpiryMonths > 0 {
+		expiresAt := now.AddDate(0, p.ExpiryMonths, 0)
+		lot.expiresAt = &expiresAt
+	}
+	m.lots = append(m.lots, lot)
+}
+
+// consumePointLots гасит amount баллов, начиная с самых ранних непросроченных партий, и возвращает
+// погашенную сумму. Если партий не хватает, ничего не меняет и возвращает ErrPointLotsShortfall — так же,
+// как откатывается транзакция в базе.
+func (m *MemoryStore) consumePointLots(userID uuid.UUID, amount models.Points, now time.Time) (models.Points, error) {
+	var available models.Points
+	for _, lot := range m.lots {
+		if lot.userID == userID && lot.remaining > 0 && !lot.expired(now) {
+			available += lot.remaining
+		}
+	}
+	if available < amount {
+		return 0, fmt.Errorf("%w: %s of %s", customerrors.ErrPointLotsShortfall, available, amount)
+	}
+
+	var consumed models.Points
+	for _, lot := range m.lots {
+		if consumed == amount {
+			break
+		}
+		if lot.userID != userID || lot.remaining <= 0 || lot.expired(now) {
+			continue
+		}
+		used := lot.remaining
+		if used > amount-consumed {
+			used = amount - consumed
+		}
+		lot.remaining -= used
+		consumed += used
+	}
+	return consumed, nil
+}
+
+func (lot *pointLot) expired(now time.Time) bool {
+	return lot.remaining > 0 && lot.expiresAt != nil && !lot.expiresAt.After(now)
+}
+
+func (m *MemoryStore) GetUsersWithExpiredPoints(_ context.Context, limit int) ([]uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	seen := make(map[uuid.UUID]bool)
+	var users []uuid.UUID
+	for _, lot := range m.lots {
+		if len(users) >= limit {
+			break
+		}
+		if lot.expired(now) && !seen[lot.userID] {
+			seen[lot.userID] = true
+			users = append(users, lot.userID)
+		}
+	}
+	return users, nil
+}
+
+func (m *MemoryStore) ExpirePoints(ctx context.Context, userID uuid.UUID) (models.Points, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.expirePointLots(ctx, userID)
+}
+
+// expirePointLots обнуляет просроченные партии пользователя и списывает их остаток проводкой expiry;
+// вызывается под m.mu, в том числе списаниями перед проверкой баланса.
+func (m *MemoryStore) expirePointLots(ctx context.Context, userID uuid.UUID) (models.Points, error) {
+	now := time.Now()
+	var expired models.Points
+	for _, lot := range m.lots {
+		if lot.userID == userID && lot.expired(now) {
+			expired += lot.remaining
+			lot.remaining = 0
+		}
+	}
+	if expired == 0 {
+		return 0, nil
+	}
+	err := m.postLedger(ctx, models.LedgerPosting{
+		UserID:         userID,
+		EntryType:      models.LedgerEntryExpiry,
+		Amount:         -expired,
+		CounterAccount: models.LedgerAccountExpired,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+func (m *MemoryStore) GetUpcomingExpirations(_ context.Context, userID uuid.UUID, limit int) ([]models.PointsExpiration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byDay := make(map[string]models.Points)
+	for _, lot := range m.lots {
+		if lot.userID == userID && lot.remaining > 0 && lot.expiresAt != nil {
+			byDay[lot.expiresAt.UTC().Format("2006-01-02")] += lot.remaining
+		}
+	}
+	result := make([]models.PointsExpiration, 0, len(byDay))
+	for day, amount := range byDay {
+		result = append(result, models.PointsExpiration{Date: day, Amount: amount})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// Списание, которое не покрывается партиями баллов, не меняет хранилище, даже если
+// баланс его позволяет.
+func TestWithdrawRollsBackOnLotShortfall(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	user, err := store.CreateUser(ctx, "user", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertOrder(ctx, user.ID, "12345678903"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateOrderAccrual(ctx, "12345678903", models.OrderStatusNew, 10000, 0); err != nil {
+		t.Fatal(err)
+	}
+	store.lots[0].remaining = 4000
+	ledger := len(store.ledger)
+
+	if err := store.Withdraw(ctx, user.ID, "2377225624", 5000); !errors.Is(err, customerrors.ErrPointLotsShortfall) {
+		t.Fatalf("Withdraw error = %v, want ErrPointLotsShortfall", err)
+	}
+	balance, err := store.GetUserBalance(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Current != 10000 || balance.Withdrawn != 0 {
+		t.Errorf("balance = %+v, want current 100, withdrawn 0", balance)
+	}
+	if store.lots[0].remaining != 4000 || len(store.ledger) != ledger || len(store.withdrawals) != 0 {
+		t.Errorf("failed withdrawal changed the store: lot %s, ledger %d -> %d, withdrawals %d",
+			store.lots[0].remaining, ledger, len(store.ledger), len(store.withdrawals))
+	}
+}
+
+// Просроченные баллы нельзя потратить и до запуска фоновой задачи: списание сначала
+// сжигает их, а уже потом сверяет баланс.
+func TestWithdrawSkipsExpiredPoints(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	user, err := store.CreateUser(ctx, "user", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for number, accrual := range map[string]models.Points{"12345678903": 10000, "79927398713": 5000} {
+		if err := store.InsertOrder(ctx, user.ID, number); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.UpdateOrderAccrual(ctx, number, models.OrderStatusNew, accrual, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, lot := range store.lots {
+		if lot.amount == 10000 {
+			expiresAt := time.Now().Add(-time.Hour)
+			lot.expiresAt = &expiresAt
+		}
+	}
+
+	if err := store.Withdraw(ctx, user.ID, "2377225624", 12000); !errors.Is(err, customerrors.ErrInsufficientBalance) {
+		t.Fatalf("Withdraw error = %v, want ErrInsufficientBalance", err)
+	}
+	if err := store.Withdraw(ctx, user.ID, "2377225624", 5000); err != nil {
+		t.Fatal(err)
+	}
+	balance, err := store.GetUserBalance(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Current != 0 || balance.Withdrawn != 5000 {
+		t.Errorf("balance = %+v, want current 0, withdrawn 50", balance)
+	}
+}
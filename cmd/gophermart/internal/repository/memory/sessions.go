@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (m *MemoryStore) CreateSession(ctx context.Context, info models.SessionInfo, hash string, ttl time.Duration) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	s := &session{
+		Session: models.Session{
+			ID:         uuid.New(),
+			Device:     info.Device,
+			IP:         info.IP,
+			UserAgent:  info.UserAgent,
+			CreatedAt:  now,
+			LastSeenAt: now,
+		},
+		userID: info.UserID,
+	}
+	m.sessions[s.ID] = s
+
+	rec := models.RefreshToken{UserID: info.UserID, SessionID: s.ID, ExpiresAt: now.Add(ttl)}
+	m.refresh[hash] = &refreshToken{RefreshToken: rec}
+	m.appendAudit(ctx, audit.LoginEvent(info, s.ID))
+	return &rec, nil
+}
+
+func (m *MemoryStore) RotateRefreshToken(_ context.Context, oldHash, newHash string, ttl time.Duration) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.refresh[oldHash]
+	if !ok {
+		return nil, customerrors.ErrInvalidRefreshToken
+	}
+	if old.rotated {
+		m.revokeSession(old.SessionID)
+		return nil, customerrors.ErrRefreshTokenReused
+	}
+	now := time.Now()
+	if old.revoked || !now.Before(old.ExpiresAt) {
+		return nil, customerrors.ErrInvalidRefreshToken
+	}
+
+	old.rotated = true
+	rec := models.RefreshToken{UserID: old.UserID, SessionID: old.SessionID, ExpiresAt: now.Add(ttl)}
+	m.refresh[newHash] = &refreshToken{RefreshToken: rec}
+	if s, ok := m.sessions[old.SessionID]; ok {
+		s.LastSeenAt = now
+	}
+	return &rec, nil
+}
+
+func (m *MemoryStore) TouchSession(_ context.Context, sessionID uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok || s.revoked {
+		return false, nil
+	}
+	s.LastSeenAt = time.Now()
+	return true, nil
+}
+
+func (m *MemoryStore) ListSessions(_ context.Context, userID uuid.UUID) ([]models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var res []models.Session
+	for _, s := range m.sessions {
+		if s.userID == userID && !s.revoked {
+			res = append(res, s.Session)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastSeenAt.After(res[j].LastSeenAt)
+	})
+	return res, nil
+}
+
+func (m *MemoryStore) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok || s.revoked || s.userID != userID {
+		return customerrors.ErrSessionNotFound
+	}
+	m.revokeSession(sessionID)
+	m.appendAudit(ctx, models.AuditEvent{Action: models.AuditSessionRevoked, UserID: &userID, Target: sessionID.String()})
+	return nil
+}
+
+func (m *MemoryStore) RevokeSessions(ctx context.Context, userID, except uuid.UUID) ([]uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []uuid.UUID
+	for id, s := range m.sessions {
+		if s.userID == userID && id != except && !s.revoked {
+			m.revokeSession(id)
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		m.appendAudit(ctx, audit.SessionsRevokedEvent(userID, ids))
+	}
+	return ids, nil
+}
+
+func (m *MemoryStore) revokeSession(sessionID uuid.UUID) {
+	if s, ok := m.sessions[sessionID]; ok {
+		s.revoked = true
+	}
+	for _, t := range m.refresh {
+		if t.SessionID == sessionID {
+			t.revoked = true
+		}
+	}
+}
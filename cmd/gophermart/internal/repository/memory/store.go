@@ -0,0 +1,693 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+type user struct {
+	models.User
+	balance   models.Points
+	withdrawn models.Points
+}
+
+type order struct {
+	models.Order
+	userID uuid.UUID
+	seq    int
+}
+
+type withdrawal struct {
+	models.Withdrawal
+	userID uuid.UUID
+	seq    int
+}
+
+type ledgerEntry struct {
+	txID         uuid.UUID
+	account      string
+	userID       uuid.UUID
+	entryType    string
+	amount       models.Points
+	orderNumber  string
+	withdrawalID *int
+	adjustmentID *uuid.UUID
+	createdAt    time.Time
+}
+
+type apiKey struct {
+	models.APIKey
+	revoked bool
+}
+
+type recoveryCode struct {
+	userID uuid.UUID
+	used   bool
+}
+
+type loginChallenge struct {
+	models.LoginChallenge
+	attempts  int
+	expiresAt time.Time
+	used      bool
+}
+
+type passwordReset struct {
+	userID    uuid.UUID
+	expiresAt time.Time
+	used      bool
+}
+
+type session struct {
+	models.Session
+	userID  uuid.UUID
+	revoked bool
+}
+
+type refreshToken struct {
+	models.RefreshToken
+	rotated bool
+	revoked bool
+}
+
+type accrualJob struct {
+	attempts    int
+	nextRunAt   time.Time
+	lockedUntil time.Time
+	lastErr     string
+}
+
+// MemoryStore — потокобезопасная реализация StoreRepositoryInterface без базы данных,
+// используется, когда DSN не задан. Все данные теряются при перезапуске.
+type MemoryStore struct {
+	mu          sync.Mutex
+	seq         int
+	users       map[uuid.UUID]*user
+	loginIndex  map[string]uuid.UUID
+	orders      map[string]*order
+	withdrawals []*withdrawal
+	ledger      []ledgerEntry
+	jobs        map[string]*accrualJob
+	idempotency map[idempotencyKey]*idempotencyEntry
+	refresh     map[string]*refreshToken
+	sessions    map[uuid.UUID]*session
+	audit       []models.AuditEvent
+	resets      map[string]*passwordReset
+	totp        map[uuid.UUID]*models.TOTP
+	recovery    map[string]*recoveryCode
+	challenges  map[string]*loginChallenge
+	apiKeys     map[string]*apiKey
+	adjustments []*models.Adjustment
+	lots        []*pointLot
+}
+
+type idempotencyKey struct {
+	userID uuid.UUID
+	key    string
+}
+
+type idempotencyEntry struct {
+	models.IdempotencyRecord
+	lockedUntil time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       make(map[uuid.UUID]*user),
+		loginIndex:  make(map[string]uuid.UUID),
+		orders:      make(map[string]*order),
+		jobs:        make(map[string]*accrualJob),
+		idempotency: make(map[idempotencyKey]*idempotencyEntry),
+		refresh:     make(map[string]*refreshToken),
+		sessions:    make(map[uuid.UUID]*session),
+		resets:      make(map[string]*passwordReset),
+		totp:        make(map[uuid.UUID]*models.TOTP),
+		recovery:    make(map[string]*recoveryCode),
+		challenges:  make(map[string]*loginChallenge),
+		apiKeys:     make(map[string]*apiKey),
+	}
+}
+
+func (m *MemoryStore) nextSeq() int {
+	m.seq++
+	return m.seq
+}
+
+func (m *MemoryStore) CreateUser(ctx context.Context, login, passwordHash string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.loginIndex[login]; ok {
+		return nil, customerrors.ErrLoginAlreadyExists
+	}
+
+	u := &user{User: models.User{ID: uuid.New(), Login: login, PasswordHash: passwordHash, Role: models.RoleUser}}
+	m.users[u.ID] = u
+	m.loginIndex[login] = u.ID
+	m.appendAudit(ctx, models.AuditEvent{
+		Action:  models.AuditUserRegistered,
+		UserID:  &u.ID,
+		Target:  login,
+		Details: map[string]interface{}{"login": login},
+	})
+
+	res := u.User
+	return &res, nil
+}
+
+func (m *MemoryStore) GetUserByLogin(_ context.Context, login string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok := m.loginIndex[login]
+	if !ok {
+		return nil, customerrors.ErrUserNotFound
+	}
+	res := m.users[id].User
+	return &res, nil
+}
+
+func (m *MemoryStore) InsertOrder(ctx context.Context, userID uuid.UUID, orderNumber string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.orders[orderNumber]; ok {
+		if existing.userID == userID {
+			return customerrors.ErrOrderAlreadyUploadedBySameUser
+		}
+		return customerrors.ErrOrderUploadedByAnotherUser
+	}
+
+	now := time.Now()
+	m.orders[orderNumber] = &order{
+		Order: models.Order{
+			Number:     orderNumber,
+			Status:     models.OrderStatusNew,
+			UploadedAt: now,
+		},
+		userID: userID,
+		seq:    m.nextSeq(),
+	}
+	m.jobs[orderNumber] = &accrualJob{nextRunAt: now}
+	m.appendAudit(ctx, models.AuditEvent{Action: models.AuditOrderUploaded, UserID: &userID, Target: orderNumber})
+	return nil
+}
+
+func (m *MemoryStore) GetOrderStatus(_ context.Context, orderNumber string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[orderNumber]
+	if !ok {
+		return "", customerrors.ErrOrderNotFound
+	}
+	return o.Status, nil
+}
+
+func (m *MemoryStore) UpdateOrderStatus(_ context.Context, orderNumber, from, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[orderNumber]
+	if !ok || o.Status != from {
+		return customerrors.ErrOrderStatusChanged
+	}
+	o.Status = to
+	return nil
+}
+
+func (m *MemoryStore) UpdateOrderAccrual(ctx context.Context, orderNumber, from string, accrual models.Points, expiryMonths int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[orderNumber]
+	if !ok || o.Status != from {
+		return customerrors.ErrOrderStatusChanged
+	}
+	if accrual > 0 {
+		err := m.postLedger(ctx, models.LedgerPosting{
+			UserID:         o.userID,
+			EntryType:      models.LedgerEntryAccrual,
+			Amount:         accrual,
+			CounterAccount: models.LedgerAccountAccrual,
+			OrderNumber:    orderNumber,
+			ExpiryMonths:   expiryMonths,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	o.Status = models.OrderStatusProcessed
+	o.Accrual = &accrual
+	return nil
+}
+
+func (m *MemoryStore) GetPendingOrders(_ context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var res []string
+	for number, o := range m.orders {
+		if o.Status == models.OrderStatusNew || o.Status == models.OrderStatusProcessing {
+			res = append(res, number)
+		}
+	}
+	return res, nil
+}
+
+func (m *MemoryStore) GetOrdersByUser(_ context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []*order
+	for _, o := range m.orders {
+		if o.userID == userID {
+			list = append(list, o)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return keyBefore(list[i].UploadedAt, list[i].Number, list[j].UploadedAt, list[j].Number) != filter.Desc
+	})
+
+	var res []models.Order
+	for _, o := range list {
+		if filter.Limit > 0 && len(res) >= filter.Limit {
+			break
+		}
+		if len(filter.Statuses) > 0 && !containsString(filter.Statuses, o.Status) {
+			continue
+		}
+		if !inListWindow(filter, o.UploadedAt, o.Number) {
+			continue
+		}
+		item := o.Order
+		if o.Accrual != nil {
+			accrual := *o.Accrual
+			item.Accrual = &accrual
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func (m *MemoryStore) Withdraw(ctx context.Context, userID uuid.UUID, orderNumber string, amount models.Points) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return customerrors.ErrUserNotFound
+	}
+	for _, w := range m.withdrawals {
+		if w.Order == orderNumber {
+			return customerrors.ErrWithdrawalOrderExists
+		}
+	}
+	if _, err := m.expirePointLots(ctx, userID); err != nil {
+		return err
+	}
+	if u.balance < amount {
+		return customerrors.ErrInsufficientBalance
+	}
+
+	w := &withdrawal{
+		userID: userID,
+		seq:    m.nextSeq(),
+	}
+	w.Withdrawal = models.Withdrawal{ID: w.seq, Order: orderNumber, Sum: amount, ProcessedAt: time.Now()}
+	err := m.postLedger(ctx, models.LedgerPosting{
+		UserID:         userID,
+		EntryType:      models.LedgerEntryWithdrawal,
+		Amount:         -amount,
+		CounterAccount: models.LedgerAccountWithdrawals,
+		OrderNumber:    orderNumber,
+		WithdrawalID:   &w.seq,
+	})
+	if err != nil {
+		return err
+	}
+	m.withdrawals = append(m.withdrawals, w)
+	return nil
+}
+
+func (m *MemoryStore) GetWithdrawals(_ context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Withdrawal, error) {
+	if filter.Cursor != nil {
+		id, err := strconv.Atoi(filter.Cursor.Key)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		filter.Cursor = &models.Cursor{At: filter.Cursor.At, Key: withdrawalKey(id)}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// withdrawals хранятся в порядке добавления, то есть по возрастанию processed_at
+	var list []models.Withdrawal
+	for _, w := range m.withdrawals {
+		if w.userID == userID {
+			list = append(list, w.Withdrawal)
+		}
+	}
+	if filter.Desc {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+
+	var res []models.Withdrawal
+	for _, w := range list {
+		if filter.Limit > 0 && len(res) >= filter.Limit {
+			break
+		}
+		if !inListWindow(filter, w.ProcessedAt, withdrawalKey(w.ID)) {
+			continue
+		}
+		res = append(res, w)
+	}
+	return res, nil
+}
+
+func (m *MemoryStore) GetUserBalance(_ context.Context, userID uuid.UUID) (*models.Balance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return nil, customerrors.ErrUserNotFound
+	}
+	return &models.Balance{Current: u.balance, Withdrawn: u.withdrawn}, nil
+}
+
+func (m *MemoryStore) EnqueueAccrualJob(_ context.Context, orderNumber string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.jobs[orderNumber]; ok {
+		return false, nil
+	}
+	if _, ok := m.orders[orderNumber]; !ok {
+		return false, customerrors.ErrOrderNotFound
+	}
+	m.jobs[orderNumber] = &accrualJob{nextRunAt: time.Now()}
+	return true, nil
+}
+
+func (m *MemoryStore) LeaseAccrualJobs(_ context.Context, limit int, lease time.Duration) ([]models.AccrualJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var due []string
+	for number, job := range m.jobs {
+		if !job.nextRunAt.After(now) && !job.lockedUntil.After(now) {
+			due = append(due, number)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return m.jobs[due[i]].nextRunAt.Before(m.jobs[due[j]].nextRunAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var res []models.AccrualJob
+	for _, number := range due {
+		job := m.jobs[number]
+		job.attempts++
+		job.lockedUntil = now.Add(lease)
+		res = append(res, models.AccrualJob{OrderNumber: number, Attempts: job.attempts})
+	}
+	return res, nil
+}
+
+func (m *MemoryStore) CompleteAccrualJob(_ context.Context, orderNumber string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.jobs, orderNumber)
+	return nil
+}
+
+func (m *MemoryStore) RescheduleAccrualJob(_ context.Context, orderNumber string, delay time.Duration, lastErr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[orderNumber]
+	if !ok {
+		return nil
+	}
+	job.nextRunAt = time.Now().Add(delay)
+	job.lockedUntil = time.Time{}
+	job.lastErr = lastErr
+	return nil
+}
+
+// postLedger вызывается под m.mu и, как и в базе, ведёт партии баллов и пишет событие money.*
+// в журнал аудита. Партии гасятся первыми: при ошибке хранилище остаётся без изменений.
+func (m *MemoryStore) postLedger(ctx context.Context, p models.LedgerPosting) error {
+	now := time.Now()
+	switch {
+	case p.Amount > 0:
+		m.addPointLot(p, now)
+	case p.EntryType != models.LedgerEntryExpiry:
+		if _, err := m.consumePointLots(p.UserID, -p.Amount, now); err != nil {
+			return err
+		}
+	}
+
+	txID := uuid.New()
+	m.ledger = append(m.ledger,
+		ledgerEntry{txID: txID, account: models.LedgerAccountUser, userID: p.UserID, entryType: p.EntryType,
+			amount: p.Amount, orderNumber: p.OrderNumber, withdrawalID: p.WithdrawalID,
+			adjustmentID: p.AdjustmentID, createdAt: now},
+		ledgerEntry{txID: txID, account: p.CounterAccount, userID: p.UserID, entryType: p.EntryType,
+			amount: -p.Amount, orderNumber: p.OrderNumber, withdrawalID: p.WithdrawalID,
+			adjustmentID: p.AdjustmentID, createdAt: now},
+	)
+
+	u := m.users[p.UserID]
+	u.balance += p.Amount
+	if p.CounterAccount == models.LedgerAccountWithdrawals {
+		u.withdrawn -= p.Amount
+	}
+	m.appendAudit(ctx, audit.LedgerEvent(p, txID, u.balance))
+	return nil
+}
+
+func (m *MemoryStore) ReconcileBalances(_ context.Context) (*models.ReconciliationReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	balances := make(map[uuid.UUID]models.Points)
+	withdrawn := make(map[uuid.UUID]models.Points)
+	txSums := make(map[uuid.UUID]models.Points)
+	var txOrder []uuid.UUID
+	for _, e := range m.ledger {
+		switch e.account {
+		case models.LedgerAccountUser:
+			balances[e.userID] += e.amount
+		case models.LedgerAccountWithdrawals:
+			withdrawn[e.userID] += e.amount
+		}
+		if _, ok := txSums[e.txID]; !ok {
+			txOrder = append(txOrder, e.txID)
+		}
+		txSums[e.txID] += e.amount
+	}
+
+	report := &models.ReconciliationReport{}
+	for id, u := range m.users {
+		if u.balance != balances[id] || u.withdrawn != withdrawn[id] {
+			report.Drifts = append(report.Drifts, models.BalanceDrift{
+				UserID:          id,
+				CachedBalance:   u.balance,
+				LedgerBalance:   balances[id],
+				CachedWithdrawn: u.withdrawn,
+				LedgerWithdrawn: withdrawn[id],
+			})
+		}
+	}
+	for _, txID := range txOrder {
+		if txSums[txID] != 0 {
+			report.UnbalancedTxIDs = append(report.UnbalancedTxIDs, txID)
+		}
+	}
+	return report, nil
+}
+
+func (m *MemoryStore) GetTransactions(_ context.Context, userID uuid.UUID, filter models.TransactionFilter) ([]models.Transaction, error) {
+	m.mu.Lock()
+	var feed []models.Transaction
+	for _, w := range m.withdrawals {
+		if w.userID == userID {
+			feed = append(feed, models.Transaction{
+				Type:       models.TransactionWithdrawal,
+				Order:      w.Order,
+				Amount:     -w.Sum,
+				OccurredAt: w.ProcessedAt,
+				Key:        "w:" + withdrawalKey(w.seq),
+			})
+		}
+	}
+	for _, a := range m.adjustments {
+		if a.UserID == userID && a.Status == models.AdjustmentApproved {
+			feed = append(feed, models.Transaction{
+				Type:       models.TransactionAdjustment,
+				Reason:     a.Reason,
+				Amount:     a.Amount,
+				OccurredAt: *a.DecidedAt,
+				Key:        "a:" + a.ID.String(),
+			})
+		}
+	}
+	for i, e := range m.ledger {
+		if e.userID != userID || e.account != models.LedgerAccountUser {
+			continue
+		}
+		switch e.entryType {
+		case models.LedgerEntryAccrual:
+			// время начисления — время проводки, а не загрузки заказа
+			feed = append(feed, models.Transaction{
+				Type:       models.TransactionAccrual,
+				Order:      e.orderNumber,
+				Amount:     e.amount,
+				OccurredAt: e.createdAt,
+				Key:        "o:" + e.orderNumber,
+			})
+		case models.LedgerEntryExpiry:
+			feed = append(feed, models.Transaction{
+				Type:       models.TransactionExpiry,
+				Amount:     e.amount,
+				OccurredAt: e.createdAt,
+				Key:        "e:" + withdrawalKey(i),
+			})
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(feed, func(i, j int) bool { return transactionBefore(feed[i], feed[j]) })
+	var balance models.Points
+	for i := range feed {
+		balance += feed[i].Amount
+		feed[i].Balance = balance
+	}
+
+	var result []models.Transaction
+	for i := len(feed) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		t := feed[i]
+		if len(filter.Types) > 0 && !containsString(filter.Types, t.Type) {
+			continue
+		}
+		if filter.From != nil && t.OccurredAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !t.OccurredAt.Before(*filter.To) {
+			continue
+		}
+		if filter.Cursor != nil && !transactionBefore(t, models.Transaction{OccurredAt: filter.Cursor.At, Key: filter.Cursor.Key}) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func transactionBefore(a, b models.Transaction) bool {
+	if !a.OccurredAt.Equal(b.OccurredAt) {
+		return a.OccurredAt.Before(b.OccurredAt)
+	}
+	return a.Key < b.Key
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func keyBefore(aAt time.Time, aKey string, bAt time.Time, bKey string) bool {
+	if !aAt.Equal(bAt) {
+		return aAt.Before(bAt)
+	}
+	return aKey < bKey
+}
+
+// inListWindow проверяет диапазон дат и положение записи относительно курсора.
+func inListWindow(filter models.ListFilter, at time.Time, key string) bool {
+	if filter.From != nil && at.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && !at.Before(*filter.To) {
+		return false
+	}
+	if filter.Cursor != nil {
+		if filter.Desc {
+			return keyBefore(at, key, filter.Cursor.At, filter.Cursor.Key)
+		}
+		return keyBefore(filter.Cursor.At, filter.Cursor.Key, at, key)
+	}
+	return true
+}
+
+// withdrawalKey дополняет id нулями, чтобы строковое сравнение ключей совпадало с числовым.
+func withdrawalKey(id int) string {
+	return fmt.Sprintf("%012d", id)
+}
+
+func (m *MemoryStore) BeginIdempotentRequest(_ context.Context, userID uuid.UUID, key, fingerprint string, lease time.Duration) (*models.IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	k := idempotencyKey{userID: userID, key: key}
+	if rec, ok := m.idempotency[k]; ok {
+		// брошенный незавершённый запрос перехватывает повтор с тем же телом
+		if rec.StatusCode == 0 && rec.Fingerprint == fingerprint && rec.lockedUntil.Before(now) {
+			rec.lockedUntil = now.Add(lease)
+			return nil, nil
+		}
+		res := rec.IdempotencyRecord
+		return &res, nil
+	}
+	m.idempotency[k] = &idempotencyEntry{
+		IdempotencyRecord: models.IdempotencyRecord{Fingerprint: fingerprint},
+		lockedUntil:       now.Add(lease),
+	}
+	return nil, nil
+}
+
+func (m *MemoryStore) CompleteIdempotentRequest(_ context.Context, userID uuid.UUID, key string, statusCode int, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rec, ok := m.idempotency[idempotencyKey{userID: userID, key: key}]; ok {
+		rec.StatusCode = statusCode
+		rec.Body = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+func (m *MemoryStore) ReleaseIdempotentRequest(_ context.Context, userID uuid.UUID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := idempotencyKey{userID: userID, key: key}
+	if rec, ok := m.idempotency[k]; ok && rec.StatusCode == 0 {
+		delete(m.idempotency, k)
+	}
+	return nil
+}
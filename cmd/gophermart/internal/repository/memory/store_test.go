@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/memory"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/repositorytest"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.StoreRepositoryInterface {
+		return memory.NewMemoryStore()
+	})
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (m *MemoryStore) GetTOTP(_ context.Context, userID uuid.UUID) (*models.TOTP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.totp[userID]
+	if !ok {
+		return nil, customerrors.ErrTOTPNotEnrolled
+	}
+	res := *t
+	return &res, nil
+}
+
+func (m *MemoryStore) SaveTOTPSecret(_ context.Context, userID uuid.UUID, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if t, ok := m.totp[userID]; ok && t.Confirmed {
+		return customerrors.ErrTOTPAlreadyEnabled
+	}
+	m.totp[userID] = &models.TOTP{Secret: secret}
+	return nil
+}
+
+func (m *MemoryStore) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.totp[userID]
+	if !ok {
+		return customerrors.ErrTOTPNotEnrolled
+	}
+	if t.Confirmed {
+		return customerrors.ErrTOTPAlreadyEnabled
+	}
+	t.Confirmed, t.LastStep = true, step
+
+	for hash, rc := range m.recovery {
+		if rc.userID == userID {
+			delete(m.recovery, hash)
+		}
+	}
+	for _, hash := range recoveryHashes {
+		m.recovery[hash] = &recoveryCode{userID: userID}
+	}
+	m.appendAudit(ctx, models.AuditEvent{Action: models.AuditTOTPEnabled, UserID: &userID})
+	return nil
+}
+
+func (m *MemoryStore) UseTOTPStep(_ context.Context, userID uuid.UUID, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.totp[userID]
+	if !ok || !t.Confirmed || t.LastStep >= step {
+		return false, nil
+	}
+	t.LastStep = step
+	return true, nil
+}
+
+func (m *MemoryStore) UseRecoveryCode(_ context.Context, userID uuid.UUID, hash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rc, ok := m.recovery[hash]
+	if !ok || rc.userID != userID || rc.used {
+		return false, nil
+	}
+	rc.used = true
+	return true, nil
+}
+
+func (m *MemoryStore) CreateLoginChallenge(_ context.Context, challenge models.LoginChallenge, hash string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.users[challenge.UserID]; ok {
+		challenge.Login = u.Login
+	}
+	m.challenges[hash] = &loginChallenge{LoginChallenge: challenge, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) GetLoginChallenge(_ context.Context, hash string, maxAttempts int) (*models.LoginChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch, ok := m.challenges[hash]
+	if !ok || ch.used || ch.attempts >= maxAttempts || !time.Now().Before(ch.expiresAt) {
+		return nil, customerrors.ErrInvalidLoginChallenge
+	}
+	res := ch.LoginChallenge
+	return &res, nil
+}
+
+func (m *MemoryStore) FailLoginChallenge(_ context.Context, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ch, ok := m.challenges[hash]; ok {
+		ch.attempts++
+	}
+	return nil
+}
+
+func (m *MemoryStore) ConsumeLoginChallenge(_ context.Context, hash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch, ok := m.challenges[hash]
+	if !ok || ch.used {
+		return false, nil
+	}
+	ch.used = true
+	return true, nil
+}
@@ -0,0 +1,175 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+const adjustmentColumns = `id, user_id, amount, reason, comment, status, proposed_by, proposed_at, decided_by, decided_at`
+
+func scanAdjustment(row pgx.Row) (*models.Adjustment, error) {
+	var a models.Adjustment
+	err := row.Scan(&a.ID, &a.UserID, &a.Amount, &a.Reason, &a.Comment, &a.Status,
+		&a.ProposedBy, &a.ProposedAt, &a.DecidedBy, &a.DecidedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, customerrors.ErrAdjustmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (d *DBStore) CreateAdjustment(ctx context.Context, adj models.Adjustment) (*models.Adjustment, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	created, err := scanAdjustment(tx.QueryRow(ctx, `
+		INSERT INTO balance_adjustments (id, user_id, amount, reason, comment, proposed_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+adjustmentColumns,
+		adj.ID, adj.UserID, adj.Amount, adj.Reason, adj.Comment, adj.ProposedBy))
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, audit.AdjustmentEvent(models.AuditAdjustmentProposed, created)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (d *DBStore) ListAdjustments(ctx context.Context, filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT `+adjustmentColumns+`
+		FROM balance_adjustments
+		WHERE ($1 = '' OR status = $1) AND ($2::uuid IS NULL OR user_id = $2)
+		ORDER BY proposed_at DESC, id
+		LIMIT $3
+	`, filter.Status, filter.UserID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.Adjustment
+	for rows.Next() {
+		a, err := scanAdjustment(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *a)
+	}
+	return list, rows.Err()
+}
+
+// ApproveAdjustment применяет корректировку проводкой по журналу в одной транзакции со сменой статуса.
+// Списание не может увести баланс в минус; просроченные баллы перед проверкой сгорают.
+func (d *DBStore) ApproveAdjustment(ctx context.Context, adjustmentID, approverID uuid.UUID, expiryMonths int) (*models.Adjustment, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	adj, err := lockPendingAdjustment(ctx, tx, adjustmentID)
+	if err != nil {
+		return nil, err
+	}
+	if adj.ProposedBy == approverID {
+		return nil, customerrors.ErrSelfApproval
+	}
+
+	if adj.Amount < 0 {
+		var balance models.Points
+		err = tx.QueryRow(ctx, `SELECT balance FROM users WHERE id = $1 FOR UPDATE`, adj.UserID).Scan(&balance)
+		if err != nil {
+			return nil, err
+		}
+		expired, err := expirePointLots(ctx, tx, adj.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if balance-expired+adj.Amount < 0 {
+			return nil, customerrors.ErrInsufficientBalance
+		}
+	}
+
+	adj, err = scanAdjustment(tx.QueryRow(ctx, `
+		UPDATE balance_adjustments SET status = 'approved', decided_by = $2, decided_at = now()
+		WHERE id = $1
+		RETURNING `+adjustmentColumns, adjustmentID, approverID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = postLedger(ctx, tx, models.LedgerPosting{
+		UserID:         adj.UserID,
+		EntryType:      models.LedgerEntryAdjustment,
+		Amount:         adj.Amount,
+		CounterAccount: models.LedgerAccountAdjustments,
+		AdjustmentID:   &adj.ID,
+		ExpiryMonths:   expiryMonths,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, audit.AdjustmentEvent(models.AuditAdjustmentApproved, adj)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return adj, nil
+}
+
+func (d *DBStore) RejectAdjustment(ctx context.Context, adjustmentID, deciderID uuid.UUID) (*models.Adjustment, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockPendingAdjustment(ctx, tx, adjustmentID); err != nil {
+		return nil, err
+	}
+	adj, err := scanAdjustment(tx.QueryRow(ctx, `
+		UPDATE balance_adjustments SET status = 'rejected', decided_by = $2, decided_at = now()
+		WHERE id = $1
+		RETURNING `+adjustmentColumns, adjustmentID, deciderID))
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, audit.AdjustmentEvent(models.AuditAdjustmentRejected, adj)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return adj, nil
+}
+
+func lockPendingAdjustment(ctx context.Context, tx pgx.Tx, adjustmentID uuid.UUID) (*models.Adjustment, error) {
+	adj, err := scanAdjustment(tx.QueryRow(ctx, `
+		SELECT `+adjustmentColumns+` FROM balance_adjustments WHERE id = $1 FOR UPDATE
+	`, adjustmentID))
+	if err != nil {
+		return nil, err
+	}
+	if adj.Status != models.AdjustmentPending {
+		return nil, customerrors.ErrAdjustmentNotPending
+	}
+	return adj, nil
+}
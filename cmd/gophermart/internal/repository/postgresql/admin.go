@@ -0,0 +1,99 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (d *DBStore) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT role FROM users WHERE id = $1 FOR NO KEY UPDATE`, userID).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customerrors.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET role = $2 WHERE id = $1`, userID, role); err != nil {
+		return err
+	}
+	err = writeAudit(ctx, tx, models.AuditEvent{
+		Action:  models.AuditUserRoleChanged,
+		UserID:  &userID,
+		Details: map[string]interface{}{"from": previous, "to": role},
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (d *DBStore) SetUserBlocked(ctx context.Context, userID uuid.UUID, blocked bool) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET blocked_at = CASE WHEN $2 THEN coalesce(blocked_at, now()) END
+		WHERE id = $1
+	`, userID, blocked)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrUserNotFound
+	}
+	action := models.AuditUserUnblocked
+	if blocked {
+		action = models.AuditUserBlocked
+	}
+	if err := writeAudit(ctx, tx, models.AuditEvent{Action: action, UserID: &userID}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RetriggerAccrualJob ставит заказ в очередь опроса немедленно, сбрасывая счётчик попыток
+// и накопленную задержку существующей задачи.
+func (d *DBStore) RetriggerAccrualJob(ctx context.Context, orderNumber string) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT user_id FROM orders WHERE number = $1`, orderNumber).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customerrors.ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO accrual_jobs (order_number) VALUES ($1)
+		ON CONFLICT (order_number) DO UPDATE SET next_run_at = now(), attempts = 0, last_error = NULL
+	`, orderNumber)
+	if err != nil {
+		return err
+	}
+	err = writeAudit(ctx, tx, models.AuditEvent{Action: models.AuditAccrualRetriggered, UserID: &userID, Target: orderNumber})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,102 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (d *DBStore) CreateAPIKey(ctx context.Context, key models.APIKey, hash string) (*models.APIKey, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`, key.ID, key.UserID, key.Name, key.Prefix, hash, key.Scopes).Scan(&key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, audit.APIKeyCreatedEvent(key)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (d *DBStore) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT id, user_id, name, prefix, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (d *DBStore) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, keyID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrAPIKeyNotFound
+	}
+	err = writeAudit(ctx, tx, models.AuditEvent{Action: models.AuditAPIKeyRevoked, UserID: &userID, Target: keyID.String()})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseAPIKey находит действующий ключ незаблокированного пользователя и отмечает время его использования.
+func (d *DBStore) UseAPIKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	var k models.APIKey
+	err := d.db.QueryRow(ctx, `
+		UPDATE api_keys k SET last_used_at = now()
+		FROM users u
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.id = k.user_id AND u.blocked_at IS NULL
+		RETURNING k.id, k.user_id, k.name, k.prefix, k.scopes, k.created_at, k.last_used_at
+	`, hash).Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, customerrors.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
@@ -0,0 +1,65 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// AttemptStore хранит счётчики неудачных входов в Postgres, чтобы их видели все реплики.
+type AttemptStore struct {
+	db *pgxpool.Pool
+}
+
+func NewAttemptStore(db *pgxpool.Pool) *AttemptStore {
+	return &AttemptStore{db: db}
+}
+
+func (a *AttemptStore) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	var seconds float64
+	err := a.db.QueryRow(ctx, `
+		SELECT COALESCE(EXTRACT(EPOCH FROM blocked_until - now()), 0)::float8 FROM auth_attempts WHERE key = $1
+	`, key).Scan(&seconds)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if seconds <= 0 {
+		return 0, nil
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// RecordFailure увеличивает счётчик; если окно window истекло, счёт начинается заново.
+func (a *AttemptStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures int
+	err := a.db.QueryRow(ctx, `
+		INSERT INTO auth_attempts (key, failures, window_started_at) VALUES ($1, 1, now())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN auth_attempts.window_started_at < now() - make_interval(secs => $2)
+				THEN 1 ELSE auth_attempts.failures + 1 END,
+			window_started_at = CASE WHEN auth_attempts.window_started_at < now() - make_interval(secs => $2)
+				THEN now() ELSE auth_attempts.window_started_at END
+		RETURNING failures
+	`, key, window.Seconds()).Scan(&failures)
+	return failures, err
+}
+
+func (a *AttemptStore) Block(ctx context.Context, key string, d time.Duration) error {
+	_, err := a.db.Exec(ctx, `
+		UPDATE auth_attempts
+		SET blocked_until = GREATEST(COALESCE(blocked_until, now()), now() + make_interval(secs => $2))
+		WHERE key = $1
+	`, key, d.Seconds())
+	return err
+}
+
+func (a *AttemptStore) Reset(ctx context.Context, key string) error {
+	_, err := a.db.Exec(ctx, `DELETE FROM auth_attempts WHERE key = $1`, key)
+	return err
+}
@@ -0,0 +1,163 @@
+package postgresql
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+const auditColumns = `id, action, actor_id, user_id, target, ip, request_id, balance_before, balance_after,
+	details, created_at, coalesce(prev_hash, ''), coalesce(hash, '')`
+
+// writeAudit добавляет запись в журнал аудита в транзакции вызывающего кода. Хеш записи
+// проставляет SealAuditChain уже после фиксации, поэтому голова цепочки здесь не блокируется.
+func writeAudit(ctx context.Context, tx pgx.Tx, e models.AuditEvent) error {
+	e = audit.NewEvent(ctx, e)
+
+	var details interface{}
+	if len(e.Details) > 0 {
+		details = e.Details
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO audit_events (action, actor_id, user_id, target, ip, request_id,
+			balance_before, balance_after, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, e.Action, e.ActorID, e.UserID, e.Target, e.IP, e.RequestID,
+		e.BalanceBefore, e.BalanceAfter, details, e.CreatedAt)
+	return err
+}
+
+// WriteAuditEvent пишет событие, не связанное с изменением данных в базе, например блокировку входа.
+func (d *DBStore) WriteAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := writeAudit(ctx, tx, event); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (d *DBStore) ListAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var beforeID *int64
+	if filter.Cursor != nil {
+		id, err := strconv.ParseInt(filter.Cursor.Key, 10, 64)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		beforeID = &id
+	}
+	rows, err := d.db.Query(ctx, `
+		SELECT `+auditColumns+`
+		FROM audit_events
+		WHERE ($1 = '' OR action = $1)
+			AND ($2::uuid IS NULL OR actor_id = $2)
+			AND ($3::uuid IS NULL OR user_id = $3)
+			AND ($4 = '' OR target = $4)
+			AND ($5 = '' OR request_id = $5)
+			AND ($6::timestamp IS NULL OR created_at >= $6)
+			AND ($7::timestamp IS NULL OR created_at < $7)
+			AND ($8::bigint IS NULL OR id < $8)
+		ORDER BY id DESC
+		LIMIT $9
+	`, filter.Action, filter.ActorID, filter.UserID, filter.Target, filter.RequestID,
+		filter.From, filter.To, beforeID, listLimit(filter.Limit))
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEvents(rows)
+}
+
+// SealAuditChain дописывает в цепочку хешей до limit зафиксированных записей без хеша в порядке id.
+// Транзакция короткая и блокирует только голову цепочки, так что ждут друг друга лишь запечатывающие.
+func (d *DBStore) SealAuditChain(ctx context.Context, limit int) (int, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var head models.AuditChainHead
+	err = tx.QueryRow(ctx, `SELECT seq, event_id, hash FROM audit_chain_head FOR UPDATE`).
+		Scan(&head.Seq, &head.EventID, &head.Hash)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT `+auditColumns+`
+		FROM audit_events
+		WHERE hash IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	events, err := scanAuditEvents(rows)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	batch := &pgx.Batch{}
+	for i := range events {
+		e := &events[i]
+		audit.Seal(e, head.Hash)
+		head.Seq++
+		head.EventID, head.Hash = e.ID, e.Hash
+		batch.Queue(`UPDATE audit_events SET chain_seq = $2, prev_hash = $3, hash = $4 WHERE id = $1`,
+			e.ID, head.Seq, e.PrevHash, e.Hash)
+	}
+	batch.Queue(`UPDATE audit_chain_head SET seq = $1, event_id = $2, hash = $3`, head.Seq, head.EventID, head.Hash)
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// GetAuditChain возвращает запечатанные записи с номером в цепочке больше afterSeq в порядке цепочки.
+func (d *DBStore) GetAuditChain(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEvent, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT `+auditColumns+`
+		FROM audit_events
+		WHERE chain_seq > $1
+		ORDER BY chain_seq
+		LIMIT $2
+	`, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEvents(rows)
+}
+
+func (d *DBStore) GetAuditChainHead(ctx context.Context) (models.AuditChainHead, error) {
+	var head models.AuditChainHead
+	err := d.db.QueryRow(ctx, `SELECT seq, event_id, hash FROM audit_chain_head`).
+		Scan(&head.Seq, &head.EventID, &head.Hash)
+	return head, err
+}
+
+func scanAuditEvents(rows pgx.Rows) ([]models.AuditEvent, error) {
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var e models.AuditEvent
+		err := rows.Scan(&e.ID, &e.Action, &e.ActorID, &e.UserID, &e.Target, &e.IP, &e.RequestID,
+			&e.BalanceBefore, &e.BalanceAfter, &e.Details, &e.CreatedAt, &e.PrevHash, &e.Hash)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
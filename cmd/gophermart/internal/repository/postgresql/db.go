@@ -2,16 +2,22 @@ package postgresql
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
 )
 
+const uniqueViolationCode = "23505"
+
 type DBStore struct {
 	db *pgxpool.Pool
 }
@@ -20,30 +26,54 @@ func NewDBStore(db *pgxpool.Pool) *DBStore {
 	return &DBStore{db: db}
 }
 
-func (d *DBStore) CreateUser(ctx context.Context, login, password string) (*models.User, error) {
-	id := uuid.New()
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
+
+func (d *DBStore) CreateUser(ctx context.Context, login, passwordHash string) (*models.User, error) {
+	tx, err := d.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
 
-	_, err = d.db.Exec(ctx,
+	id := uuid.New()
+	_, err = tx.Exec(ctx,
 		`INSERT INTO users (id, login, password_hash) VALUES ($1, $2, $3)`,
-		id, login, string(hash),
+		id, login, passwordHash,
 	)
+	if isUniqueViolation(err) {
+		return nil, customerrors.ErrLoginAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = writeAudit(ctx, tx, models.AuditEvent{
+		Action:  models.AuditUserRegistered,
+		UserID:  &id,
+		Target:  login,
+		Details: map[string]interface{}{"login": login},
+	})
 	if err != nil {
 		return nil, err
 	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 
-	return &models.User{ID: id, Login: login, PasswordHash: string(hash)}, nil
+	return &models.User{ID: id, Login: login, PasswordHash: passwordHash, Role: models.RoleUser}, nil
 }
 
 func (d *DBStore) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
 	row := d.db.QueryRow(ctx,
-		`SELECT id, login, password_hash FROM users WHERE login = $1`, login)
+		`SELECT id, login, password_hash, role, blocked_at IS NOT NULL FROM users WHERE login = $1`, login)
 
 	var u models.User
-	err := row.Scan(&u.ID, &u.Login, &u.PasswordHash)
+	err := row.Scan(&u.ID, &u.Login, &u.PasswordHash, &u.Role, &u.Blocked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, customerrors.ErrUserNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -63,42 +93,96 @@ func (d *DBStore) InsertOrder(ctx context.Context, userID uuid.UUID, orderNumber
 		return customerrors.ErrOrderUploadedByAnotherUser
 	}
 
-	_, err = d.db.Exec(ctx, `
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
 		INSERT INTO orders (number, user_id, status, uploaded_at)
 		VALUES ($1, $2, 'NEW', now())
 	`, orderNumber, userID)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// задача на опрос создаётся в той же транзакции, чтобы заказ не потерялся
+	_, err = tx.Exec(ctx, `
+		INSERT INTO accrual_jobs (order_number) VALUES ($1)
+	`, orderNumber)
+	if err != nil {
+		return err
+	}
+	err = writeAudit(ctx, tx, models.AuditEvent{Action: models.AuditOrderUploaded, UserID: &userID, Target: orderNumber})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
-func (d *DBStore) UpdateOrderAccrual(ctx context.Context, orderNumber, status string, accrual float64) error {
+func (d *DBStore) GetOrderStatus(ctx context.Context, orderNumber string) (string, error) {
+	var status string
+	err := d.db.QueryRow(ctx, `SELECT status FROM orders WHERE number = $1`, orderNumber).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", customerrors.ErrOrderNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+func (d *DBStore) UpdateOrderStatus(ctx context.Context, orderNumber, from, to string) error {
+	tag, err := d.db.Exec(ctx, `
+		UPDATE orders SET status = $1 WHERE number = $2 AND status = $3
+	`, to, orderNumber, from)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrOrderStatusChanged
+	}
+	return nil
+}
+
+func (d *DBStore) UpdateOrderAccrual(ctx context.Context, orderNumber, from string, accrual models.Points, expiryMonths int) error {
 	tx, err := d.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	// обновить заказ
-	var userID string
+	// обновить заказ, только если он всё ещё в ожидаемом статусе, иначе баланс пополнится дважды
+	var userID uuid.UUID
 	err = tx.QueryRow(ctx, `
 		UPDATE orders
-		SET status = $1, accrual = $2
-		WHERE number = $3
+		SET status = 'PROCESSED', accrual = $1
+		WHERE number = $2 AND status = $3
 		RETURNING user_id
-	`, status, accrual, orderNumber).Scan(&userID)
-	if err != nil {
-		return err
+	`, accrual, orderNumber, from).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customerrors.ErrOrderStatusChanged
 	}
-	log.Printf("row: %v", userID)
-	// пополнить баланс
-	_, err = tx.Exec(ctx, `
-		UPDATE users
-		SET balance = balance + $1
-		WHERE id = $2
-	`, accrual, userID)
 	if err != nil {
 		return err
 	}
 
+	if accrual > 0 {
+		err = postLedger(ctx, tx, models.LedgerPosting{
+			UserID:         userID,
+			EntryType:      models.LedgerEntryAccrual,
+			Amount:         accrual,
+			CounterAccount: models.LedgerAccountAccrual,
+			OrderNumber:    orderNumber,
+			ExpiryMonths:   expiryMonths,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -123,11 +207,39 @@ func (d *DBStore) GetPendingOrders(ctx context.Context) ([]string, error) {
 	return orders, nil
 }
 
-func (d *DBStore) GetOrdersByUser(ctx context.Context, userID uuid.UUID) ([]models.Order, error) {
-	rows, err := d.db.Query(ctx, `
-	SELECT number, status, accrual, uploaded_at FROM orders WHERE user_id = $1
-	ORDER BY uploaded_at DESC
-	`, userID)
+// listDirection возвращает направление сортировки и оператор сравнения с курсором.
+func listDirection(desc bool) (string, string) {
+	if desc {
+		return "DESC", "<"
+	}
+	return "ASC", ">"
+}
+
+func listLimit(limit int) *int {
+	if limit <= 0 {
+		return nil
+	}
+	return &limit
+}
+
+func (d *DBStore) GetOrdersByUser(ctx context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Order, error) {
+	dir, cmp := listDirection(filter.Desc)
+	var cursorAt *time.Time
+	var cursorKey string
+	if filter.Cursor != nil {
+		cursorAt, cursorKey = &filter.Cursor.At, filter.Cursor.Key
+	}
+
+	rows, err := d.db.Query(ctx, fmt.Sprintf(`
+	SELECT number, status, accrual, uploaded_at FROM orders
+	WHERE user_id = $1
+		AND ($2::text[] IS NULL OR status = ANY($2))
+		AND ($3::timestamp IS NULL OR uploaded_at >= $3)
+		AND ($4::timestamp IS NULL OR uploaded_at < $4)
+		AND ($5::timestamp IS NULL OR (uploaded_at, number) %[2]s ($5, $6::text))
+	ORDER BY uploaded_at %[1]s, number %[1]s
+	LIMIT $7
+	`, dir, cmp), userID, filter.Statuses, filter.From, filter.To, cursorAt, cursorKey, listLimit(filter.Limit))
 	if err != nil {
 		return nil, err
 	}
@@ -141,35 +253,58 @@ func (d *DBStore) GetOrdersByUser(ctx context.Context, userID uuid.UUID) ([]mode
 		}
 		orders = append(orders, order)
 	}
-	return orders, nil
+	return orders, rows.Err()
 }
 
-func (d *DBStore) Withdraw(ctx context.Context, userID uuid.UUID, order string, amount float64) error {
+func (d *DBStore) Withdraw(ctx context.Context, userID uuid.UUID, order string, amount models.Points) error {
 	tx, err := d.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	var currentBalance float64
+	var currentBalance models.Points
 	err = tx.QueryRow(ctx, `SELECT balance FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&currentBalance)
 	if err != nil {
 		return err
 	}
-	if currentBalance < amount {
+	// повтор номера заказа — 409 независимо от баланса; гонку двух вставок ловит уникальный индекс ниже
+	var orderUsed bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM withdrawals WHERE order_number = $1)`, order).Scan(&orderUsed)
+	if err != nil {
+		return err
+	}
+	if orderUsed {
+		return customerrors.ErrWithdrawalOrderExists
+	}
+	expired, err := expirePointLots(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if currentBalance-expired < amount {
 		return customerrors.ErrInsufficientBalance
 	}
 
-	_, err = tx.Exec(ctx, `
+	var withdrawalID int
+	err = tx.QueryRow(ctx, `
 		INSERT INTO withdrawals (user_id, order_number, amount) VALUES ($1, $2, $3)
-	`, userID, order, amount)
+		RETURNING id
+	`, userID, order, amount).Scan(&withdrawalID)
+	if isUniqueViolation(err) {
+		return customerrors.ErrWithdrawalOrderExists
+	}
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(ctx, `
-		UPDATE users SET balance = balance - $1, withdrawn = withdrawn + $1 WHERE id = $2
-	`, amount, userID)
+	err = postLedger(ctx, tx, models.LedgerPosting{
+		UserID:         userID,
+		EntryType:      models.LedgerEntryWithdrawal,
+		Amount:         -amount,
+		CounterAccount: models.LedgerAccountWithdrawals,
+		OrderNumber:    order,
+		WithdrawalID:   &withdrawalID,
+	})
 	if err != nil {
 		return err
 	}
@@ -177,13 +312,28 @@ func (d *DBStore) Withdraw(ctx context.Context, userID uuid.UUID, order string,
 	return tx.Commit(ctx)
 }
 
-func (d *DBStore) GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]models.Withdrawal, error) {
-	rows, err := d.db.Query(ctx, `
-	SELECT order_number, amount, processed_at
+func (d *DBStore) GetWithdrawals(ctx context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Withdrawal, error) {
+	dir, cmp := listDirection(filter.Desc)
+	var cursorAt *time.Time
+	var cursorID *int
+	if filter.Cursor != nil {
+		id, err := strconv.Atoi(filter.Cursor.Key)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		cursorAt, cursorID = &filter.Cursor.At, &id
+	}
+
+	rows, err := d.db.Query(ctx, fmt.Sprintf(`
+	SELECT id, order_number, amount, processed_at
 	FROM withdrawals
 	WHERE user_id = $1
-	ORDER BY processed_at ASC
-	`, userID)
+		AND ($2::timestamp IS NULL OR processed_at >= $2)
+		AND ($3::timestamp IS NULL OR processed_at < $3)
+		AND ($4::timestamp IS NULL OR (processed_at, id) %[2]s ($4, $5::int))
+	ORDER BY processed_at %[1]s, id %[1]s
+	LIMIT $6
+	`, dir, cmp), userID, filter.From, filter.To, cursorAt, cursorID, listLimit(filter.Limit))
 	if err != nil {
 		return nil, err
 	}
@@ -192,12 +342,12 @@ func (d *DBStore) GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]model
 	var result []models.Withdrawal
 	for rows.Next() {
 		var w models.Withdrawal
-		if err := rows.Scan(&w.Order, &w.Sum, &w.ProcessedAt); err != nil {
+		if err := rows.Scan(&w.ID, &w.Order, &w.Sum, &w.ProcessedAt); err != nil {
 			return nil, err
 		}
 		result = append(result, w)
 	}
-	return result, nil
+	return result, rows.Err()
 }
 
 func (d *DBStore) GetUserBalance(ctx context.Context, userID uuid.UUID) (*models.Balance, error) {
@@ -211,3 +361,57 @@ func (d *DBStore) GetUserBalance(ctx context.Context, userID uuid.UUID) (*models
 
 	return &balance, nil
 }
+
+func (d *DBStore) EnqueueAccrualJob(ctx context.Context, orderNumber string) (bool, error) {
+	tag, err := d.db.Exec(ctx, `
+		INSERT INTO accrual_jobs (order_number) VALUES ($1)
+		ON CONFLICT (order_number) DO NOTHING
+	`, orderNumber)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (d *DBStore) LeaseAccrualJobs(ctx context.Context, limit int, lease time.Duration) ([]models.AccrualJob, error) {
+	rows, err := d.db.Query(ctx, `
+		UPDATE accrual_jobs
+		SET locked_until = now() + make_interval(secs => $2), attempts = attempts + 1
+		WHERE order_number IN (
+			SELECT order_number FROM accrual_jobs
+			WHERE next_run_at <= now() AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY next_run_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING order_number, attempts
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.AccrualJob
+	for rows.Next() {
+		var job models.AccrualJob
+		if err := rows.Scan(&job.OrderNumber, &job.Attempts); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (d *DBStore) CompleteAccrualJob(ctx context.Context, orderNumber string) error {
+	_, err := d.db.Exec(ctx, `DELETE FROM accrual_jobs WHERE order_number = $1`, orderNumber)
+	return err
+}
+
+func (d *DBStore) RescheduleAccrualJob(ctx context.Context, orderNumber string, delay time.Duration, lastErr string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE accrual_jobs
+		SET next_run_at = now() + make_interval(secs => $2), locked_until = NULL, last_error = NULLIF($3, '')
+		WHERE order_number = $1
+	`, orderNumber, delay.Seconds(), lastErr)
+	return err
+}
@@ -0,0 +1,59 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+// BeginIdempotentRequest резервирует ключ на lease; если ключ уже был, возвращает его запись.
+// Незавершённую запись с истёкшей арендой (процесс упал посреди запроса) перехватывает повтор
+// с тем же телом запроса.
+func (d *DBStore) BeginIdempotentRequest(ctx context.Context, userID uuid.UUID, key, fingerprint string, lease time.Duration) (*models.IdempotencyRecord, error) {
+	tag, err := d.db.Exec(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, locked_until)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		ON CONFLICT (user_id, key) DO UPDATE SET locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.status_code IS NULL
+			AND idempotency_keys.fingerprint = EXCLUDED.fingerprint
+			AND idempotency_keys.locked_until < now()
+	`, userID, key, fingerprint, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil, nil
+	}
+
+	var rec models.IdempotencyRecord
+	var status *int
+	err = d.db.QueryRow(ctx, `
+		SELECT fingerprint, status_code, response_body FROM idempotency_keys WHERE user_id = $1 AND key = $2
+	`, userID, key).Scan(&rec.Fingerprint, &status, &rec.Body)
+	if err != nil {
+		return nil, err
+	}
+	if status != nil {
+		rec.StatusCode = *status
+	}
+	return &rec, nil
+}
+
+func (d *DBStore) CompleteIdempotentRequest(ctx context.Context, userID uuid.UUID, key string, statusCode int, body []byte) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE idempotency_keys SET status_code = $3, response_body = $4, completed_at = now()
+		WHERE user_id = $1 AND key = $2
+	`, userID, key, statusCode, body)
+	return err
+}
+
+// ReleaseIdempotentRequest удаляет незавершённую запись, чтобы запрос можно было повторить.
+func (d *DBStore) ReleaseIdempotentRequest(ctx context.Context, userID uuid.UUID, key string) error {
+	_, err := d.db.Exec(ctx, `
+		DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL
+	`, userID, key)
+	return err
+}
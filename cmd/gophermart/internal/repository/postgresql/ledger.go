@@ -0,0 +1,104 @@
+package postgresql
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+// postLedger записывает двойную проводку, ведёт партии баллов, обновляет кешированные
+// users.balance/withdrawn и пишет в журнал аудита событие money.* с балансом до и после
+// проводки — всё в транзакции вызывающего кода. Партии блокируются после строки пользователя.
+func postLedger(ctx context.Context, tx pgx.Tx, p models.LedgerPosting) error {
+	txID := uuid.New()
+	var orderNumber *string
+	if p.OrderNumber != "" {
+		orderNumber = &p.OrderNumber
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO ledger_entries (tx_id, account, user_id, entry_type, amount, order_number, withdrawal_id, adjustment_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $9), ($1, $8, $3, $4, -$5::numeric, $6, $7, $9)
+	`, txID, models.LedgerAccountUser, p.UserID, p.EntryType, p.Amount, orderNumber, p.WithdrawalID, p.CounterAccount, p.AdjustmentID)
+	if err != nil {
+		return err
+	}
+
+	// сгорание гасит конкретные просроченные партии само, остальные списания — по порядку поступления
+	switch {
+	case p.Amount > 0:
+		err = addPointLot(ctx, tx, p)
+	case p.EntryType != models.LedgerEntryExpiry:
+		_, err = consumePointLots(ctx, tx, p.UserID, -p.Amount)
+	}
+	if err != nil {
+		return err
+	}
+
+	withdrawn := models.Points(0)
+	if p.CounterAccount == models.LedgerAccountWithdrawals {
+		withdrawn = -p.Amount
+	}
+	var after models.Points
+	err = tx.QueryRow(ctx, `
+		UPDATE users SET balance = balance + $1, withdrawn = withdrawn + $2 WHERE id = $3
+		RETURNING balance
+	`, p.Amount, withdrawn, p.UserID).Scan(&after)
+	if err != nil {
+		return err
+	}
+	return writeAudit(ctx, tx, audit.LedgerEvent(p, txID, after))
+}
+
+func (d *DBStore) ReconcileBalances(ctx context.Context) (*models.ReconciliationReport, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT u.id, u.balance, COALESCE(l.balance, 0), u.withdrawn, COALESCE(l.withdrawn, 0)
+		FROM users u
+		LEFT JOIN (
+			SELECT user_id,
+				SUM(amount) FILTER (WHERE account = 'user') AS balance,
+				SUM(amount) FILTER (WHERE account = 'withdrawals') AS withdrawn
+			FROM ledger_entries
+			GROUP BY user_id
+		) l ON l.user_id = u.id
+		WHERE COALESCE(u.balance, 0) <> COALESCE(l.balance, 0)
+			OR COALESCE(u.withdrawn, 0) <> COALESCE(l.withdrawn, 0)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.ReconciliationReport{}
+	for rows.Next() {
+		var drift models.BalanceDrift
+		if err := rows.Scan(&drift.UserID, &drift.CachedBalance, &drift.LedgerBalance, &drift.CachedWithdrawn, &drift.LedgerWithdrawn); err != nil {
+			return nil, err
+		}
+		report.Drifts = append(report.Drifts, drift)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	txRows, err := d.db.Query(ctx, `
+		SELECT tx_id FROM ledger_entries GROUP BY tx_id HAVING SUM(amount) <> 0
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer txRows.Close()
+
+	for txRows.Next() {
+		var txID uuid.UUID
+		if err := txRows.Scan(&txID); err != nil {
+			return nil, err
+		}
+		report.UnbalancedTxIDs = append(report.UnbalancedTxIDs, txID)
+	}
+	return report, txRows.Err()
+}
@@ -0,0 +1,62 @@
+package postgresql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/postgresql"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/postgresql/pgtest"
+)
+
+// TestMigrateDuplicateWithdrawalOrders: уникальный индекс на номер заказа списания
+// применяется к базе, где один номер уже списан дважды.
+func TestMigrateDuplicateWithdrawalOrders(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.NewDatabase(t)
+
+	states, err := postgresql.MigrationStatus(ctx, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// откатываемся к схеме до 0005_idempotency
+	if _, err := postgresql.MigrateDown(ctx, pool, len(states)-4); err != nil {
+		t.Fatalf("migrate down: %v", err)
+	}
+
+	userID := uuid.New()
+	if _, err := pool.Exec(ctx, `INSERT INTO users (id, login, password_hash) VALUES ($1, 'user', 'hash')`, userID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO withdrawals (user_id, order_number, amount, processed_at) VALUES
+			($1, '2377225624', 10, now() - interval '2 hours'),
+			($1, '2377225624', 20, now() - interval '1 hour'),
+			($1, '2377225608', 30, now())
+	`, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := postgresql.MigrateUp(ctx, pool); err != nil {
+		t.Fatalf("migrate up with duplicate withdrawal orders: %v", err)
+	}
+
+	var kept, renamed, original string
+	err = pool.QueryRow(ctx, `SELECT order_number FROM withdrawals WHERE amount = 10`).Scan(&kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pool.QueryRow(ctx, `
+		SELECT w.order_number, c.order_number
+		FROM withdrawals w JOIN withdrawal_order_conflicts c ON c.withdrawal_id = w.id
+		WHERE w.amount = 20
+	`).Scan(&renamed, &original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept != "2377225624" || original != "2377225624" || renamed == original {
+		t.Errorf("kept %q, renamed %q from %q", kept, renamed, original)
+	}
+}
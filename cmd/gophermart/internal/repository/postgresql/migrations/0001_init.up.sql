@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS users (
+	id UUID PRIMARY KEY,
+	login TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	balance NUMERIC(18, 2) DEFAULT 0,
+	withdrawn NUMERIC(18, 2) DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+	number TEXT PRIMARY KEY,
+	user_id UUID REFERENCES users(id),
+	status TEXT NOT NULL,
+	accrual NUMERIC(18, 2),
+	uploaded_at TIMESTAMP DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS withdrawals (
+	id SERIAL PRIMARY KEY,
+	user_id UUID REFERENCES users(id),
+	order_number TEXT NOT NULL,
+	amount NUMERIC(18, 2) NOT NULL,
+	processed_at TIMESTAMP DEFAULT now()
+);
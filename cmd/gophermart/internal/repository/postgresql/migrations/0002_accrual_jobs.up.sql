@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS accrual_jobs (
+	order_number TEXT PRIMARY KEY REFERENCES orders(number),
+	attempts INT NOT NULL DEFAULT 0,
+	next_run_at TIMESTAMP NOT NULL DEFAULT now(),
+	locked_until TIMESTAMP,
+	last_error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS accrual_jobs_next_run_at_idx ON accrual_jobs (next_run_at);
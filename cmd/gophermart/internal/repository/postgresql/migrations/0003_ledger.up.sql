@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	id BIGSERIAL PRIMARY KEY,
+	tx_id UUID NOT NULL,
+	account TEXT NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id),
+	entry_type TEXT NOT NULL,
+	amount NUMERIC(18, 2) NOT NULL,
+	order_number TEXT,
+	withdrawal_id INT REFERENCES withdrawals(id),
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_user_account_idx ON ledger_entries (user_id, account);
+CREATE INDEX IF NOT EXISTS ledger_entries_tx_id_idx ON ledger_entries (tx_id);
+
+CREATE OR REPLACE FUNCTION ledger_entries_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'ledger_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_append_only
+	BEFORE UPDATE OR DELETE ON ledger_entries
+	FOR EACH ROW EXECUTE FUNCTION ledger_entries_append_only();
+
+-- проводки по уже начисленным и списанным баллам; идентификаторы проводок собираются из md5,
+-- чтобы не требовать gen_random_uuid() (PostgreSQL 13+ или расширение pgcrypto)
+INSERT INTO ledger_entries (tx_id, account, user_id, entry_type, amount, order_number, created_at)
+SELECT t.tx_id, a.account, t.user_id, 'accrual', a.sign * t.accrual, t.number, t.uploaded_at
+FROM (
+	SELECT md5(random()::text || clock_timestamp()::text)::uuid AS tx_id, number, user_id, accrual, uploaded_at
+	FROM orders
+	WHERE status = 'PROCESSED' AND accrual > 0
+) t
+CROSS JOIN (VALUES ('user', 1), ('accrual', -1)) AS a(account, sign);
+
+INSERT INTO ledger_entries (tx_id, account, user_id, entry_type, amount, withdrawal_id, order_number, created_at)
+SELECT t.tx_id, a.account, t.user_id, 'withdrawal', a.sign * t.amount, t.id, t.order_number, t.processed_at
+FROM (
+	SELECT md5(random()::text || clock_timestamp()::text)::uuid AS tx_id, id, user_id, order_number, amount, processed_at
+	FROM withdrawals
+) t
+CROSS JOIN (VALUES ('user', -1), ('withdrawals', 1)) AS a(account, sign);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS withdrawals_order_number_key;
+
+UPDATE withdrawals w SET order_number = c.order_number
+FROM withdrawal_order_conflicts c
+WHERE c.withdrawal_id = w.id;
+DROP TABLE IF EXISTS withdrawal_order_conflicts;
+
+DROP TABLE IF EXISTS idempotency_keys;
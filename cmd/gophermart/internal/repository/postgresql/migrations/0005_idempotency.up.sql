@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	user_id UUID NOT NULL REFERENCES users(id),
+	key TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	status_code INT,
+	response_body BYTEA,
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	completed_at TIMESTAMP,
+	locked_until TIMESTAMP,
+	PRIMARY KEY (user_id, key)
+);
+
+-- До уникального индекса один номер заказа можно было списать несколько раз. Самое раннее
+-- списание сохраняет номер, остальные получают номер с суффиксом '#dup-<id>', а исходные
+-- номера остаются в withdrawal_order_conflicts для разбора. Суммы и проводки не меняются.
+CREATE TABLE IF NOT EXISTS withdrawal_order_conflicts (
+	withdrawal_id INT PRIMARY KEY REFERENCES withdrawals(id),
+	order_number TEXT NOT NULL,
+	renamed_to TEXT NOT NULL,
+	detected_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+INSERT INTO withdrawal_order_conflicts (withdrawal_id, order_number, renamed_to)
+SELECT id, order_number, order_number || '#dup-' || id
+FROM (
+	SELECT id, order_number,
+		row_number() OVER (PARTITION BY order_number ORDER BY processed_at, id) AS n
+	FROM withdrawals
+) w
+WHERE n > 1;
+
+UPDATE withdrawals w SET order_number = c.renamed_to
+FROM withdrawal_order_conflicts c
+WHERE c.withdrawal_id = w.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS withdrawals_order_number_key ON withdrawals (order_number);
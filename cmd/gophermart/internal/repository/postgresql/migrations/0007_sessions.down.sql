@@ -0,0 +1,4 @@
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_session_fkey;
+ALTER INDEX refresh_tokens_session_idx RENAME TO refresh_tokens_family_idx;
+ALTER TABLE refresh_tokens RENAME COLUMN session_id TO family_id;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS sessions (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id),
+	device TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	last_seen_at TIMESTAMP NOT NULL DEFAULT now(),
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_idx ON sessions (user_id) WHERE revoked_at IS NULL;
+
+-- каждое семейство refresh-токенов, выданных до появления сессий, становится сессией
+INSERT INTO sessions (id, user_id, created_at, last_seen_at, revoked_at)
+SELECT family_id, user_id, min(created_at), max(created_at),
+	CASE WHEN bool_and(revoked_at IS NOT NULL) THEN max(revoked_at) END
+FROM refresh_tokens
+GROUP BY family_id, user_id
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE refresh_tokens RENAME COLUMN family_id TO session_id;
+ALTER INDEX refresh_tokens_family_idx RENAME TO refresh_tokens_session_idx;
+ALTER TABLE refresh_tokens
+	ADD CONSTRAINT refresh_tokens_session_fkey FOREIGN KEY (session_id) REFERENCES sessions(id);
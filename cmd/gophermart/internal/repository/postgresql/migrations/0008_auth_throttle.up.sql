@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS auth_attempts (
+	key TEXT PRIMARY KEY,
+	failures INT NOT NULL DEFAULT 0,
+	window_started_at TIMESTAMP NOT NULL DEFAULT now(),
+	blocked_until TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+	id BIGSERIAL PRIMARY KEY,
+	event_type TEXT NOT NULL,
+	user_id UUID REFERENCES users(id),
+	subject TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '',
+	details JSONB,
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_idx ON audit_events (created_at);
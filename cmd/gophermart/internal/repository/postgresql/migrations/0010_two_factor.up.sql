@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id UUID PRIMARY KEY REFERENCES users(id),
+	secret TEXT NOT NULL,
+	confirmed_at TIMESTAMP,
+	last_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	code_hash TEXT PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id),
+	used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_idx ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS login_challenges (
+	token_hash TEXT PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id),
+	device TEXT NOT NULL DEFAULT '',
+	attempts INT NOT NULL DEFAULT 0,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP
+);
@@ -0,0 +1,2 @@
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS adjustment_id;
+DROP TABLE IF EXISTS balance_adjustments;
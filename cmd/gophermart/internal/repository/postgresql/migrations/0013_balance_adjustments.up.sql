@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id),
+	amount NUMERIC(18, 2) NOT NULL CHECK (amount <> 0),
+	reason TEXT NOT NULL,
+	comment TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+	proposed_by UUID NOT NULL REFERENCES users(id),
+	proposed_at TIMESTAMP NOT NULL DEFAULT now(),
+	decided_by UUID REFERENCES users(id),
+	decided_at TIMESTAMP,
+	-- принцип четырёх глаз: автор не может одобрить свою корректировку
+	CONSTRAINT balance_adjustments_four_eyes CHECK (status <> 'approved' OR decided_by <> proposed_by)
+);
+
+CREATE INDEX IF NOT EXISTS balance_adjustments_status_idx ON balance_adjustments (status, proposed_at);
+CREATE INDEX IF NOT EXISTS balance_adjustments_user_idx ON balance_adjustments (user_id);
+
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS adjustment_id UUID REFERENCES balance_adjustments(id);
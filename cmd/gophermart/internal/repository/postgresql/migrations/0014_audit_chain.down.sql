@@ -0,0 +1,19 @@
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+DROP TABLE IF EXISTS audit_chain_head;
+
+DROP INDEX IF EXISTS audit_events_request_idx;
+DROP INDEX IF EXISTS audit_events_actor_idx;
+DROP INDEX IF EXISTS audit_events_user_idx;
+DROP INDEX IF EXISTS audit_events_action_idx;
+
+ALTER TABLE audit_events
+	DROP COLUMN IF EXISTS hash,
+	DROP COLUMN IF EXISTS prev_hash,
+	DROP COLUMN IF EXISTS balance_after,
+	DROP COLUMN IF EXISTS balance_before,
+	DROP COLUMN IF EXISTS request_id,
+	DROP COLUMN IF EXISTS actor_id;
+ALTER TABLE audit_events RENAME COLUMN target TO subject;
+ALTER TABLE audit_events ADD CONSTRAINT audit_events_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+ALTER TABLE audit_events RENAME COLUMN action TO event_type;
@@ -0,0 +1,37 @@
+ALTER TABLE audit_events RENAME COLUMN event_type TO action;
+ALTER TABLE audit_events RENAME COLUMN subject TO target;
+-- журнал пишется последним шагом транзакции под блокировкой головы цепочки; проверка
+-- внешнего ключа в этот момент ждала бы блокировку строки users и могла бы привести к взаимной блокировке
+ALTER TABLE audit_events DROP CONSTRAINT IF EXISTS audit_events_user_id_fkey;
+ALTER TABLE audit_events
+	ADD COLUMN IF NOT EXISTS actor_id UUID,
+	ADD COLUMN IF NOT EXISTS request_id TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS balance_before NUMERIC(18, 2),
+	ADD COLUMN IF NOT EXISTS balance_after NUMERIC(18, 2),
+	ADD COLUMN IF NOT EXISTS prev_hash TEXT,
+	ADD COLUMN IF NOT EXISTS hash TEXT;
+
+CREATE INDEX IF NOT EXISTS audit_events_action_idx ON audit_events (action, id);
+CREATE INDEX IF NOT EXISTS audit_events_user_idx ON audit_events (user_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_request_idx ON audit_events (request_id) WHERE request_id <> '';
+
+-- голова цепочки хешей: строка блокируется до конца транзакции, которая пишет в журнал,
+-- поэтому записи выстраиваются в цепочку строго по очереди. Записи, сделанные до этой
+-- миграции, остаются без хеша и в проверку цепочки не входят.
+CREATE TABLE IF NOT EXISTS audit_chain_head (
+	id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+	event_id BIGINT NOT NULL DEFAULT 0,
+	hash TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO audit_chain_head (id) VALUES (true) ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+	BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
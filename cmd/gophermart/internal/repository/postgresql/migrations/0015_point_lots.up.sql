@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS point_lots (
+	id BIGSERIAL PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id),
+	entry_type TEXT NOT NULL,
+	order_number TEXT,
+	adjustment_id UUID REFERENCES balance_adjustments(id),
+	amount NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+	remaining NUMERIC(18, 2) NOT NULL CHECK (remaining >= 0 AND remaining <= amount),
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	expires_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS point_lots_open_idx ON point_lots (user_id, id) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS point_lots_expiry_idx ON point_lots (expires_at) WHERE remaining > 0 AND expires_at IS NOT NULL;
+
+-- баллы, накопленные до введения партий, переносятся одной бессрочной партией
+INSERT INTO point_lots (user_id, entry_type, amount, remaining)
+SELECT id, 'opening', balance, balance
+FROM users
+WHERE balance > 0;
@@ -0,0 +1,9 @@
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS audit_events_unsealed_idx;
+ALTER TABLE audit_chain_head DROP COLUMN IF EXISTS seq;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS chain_seq;
@@ -0,0 +1,26 @@
+-- цепочку хешей достраивает фоновая задача: бизнес-транзакции пишут запись без хеша и голову
+-- цепочки не блокируют. Порядок в цепочке — порядок запечатывания (chain_seq), а не id:
+-- запись с меньшим id может зафиксироваться позже записи с большим.
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS chain_seq BIGINT UNIQUE;
+ALTER TABLE audit_chain_head ADD COLUMN IF NOT EXISTS seq BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE audit_events DISABLE TRIGGER audit_events_append_only;
+UPDATE audit_events a SET chain_seq = s.seq
+FROM (SELECT id, row_number() OVER (ORDER BY id) AS seq FROM audit_events WHERE hash IS NOT NULL) s
+WHERE a.id = s.id;
+ALTER TABLE audit_events ENABLE TRIGGER audit_events_append_only;
+UPDATE audit_chain_head SET seq = (SELECT count(*) FROM audit_events WHERE hash IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS audit_events_unsealed_idx ON audit_events (id) WHERE hash IS NULL;
+
+-- единственное разрешённое изменение — запечатать запись: проставить chain_seq, prev_hash и hash
+-- там, где их ещё нет, не трогая остальных полей
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'UPDATE' AND OLD.hash IS NULL AND NEW.hash IS NOT NULL
+		AND to_jsonb(NEW) - 'chain_seq' - 'prev_hash' - 'hash' = to_jsonb(OLD) - 'chain_seq' - 'prev_hash' - 'hash' THEN
+		RETURN NEW;
+	END IF;
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
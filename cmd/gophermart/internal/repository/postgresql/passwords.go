@@ -0,0 +1,119 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (d *DBStore) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	var u models.User
+	err := d.db.QueryRow(ctx,
+		`SELECT id, login, password_hash, role, blocked_at IS NOT NULL FROM users WHERE id = $1`, userID,
+	).Scan(&u.ID, &u.Login, &u.PasswordHash, &u.Role, &u.Blocked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, customerrors.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (d *DBStore) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrUserNotFound
+	}
+	if err := writeAudit(ctx, tx, models.AuditEvent{Action: models.AuditPasswordChanged, UserID: &userID}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RehashPassword заменяет хеш тем же паролем с новой стоимостью, только если хеш не успели
+// сменить; пароль при этом не меняется, поэтому в журнал аудита ничего не пишется.
+func (d *DBStore) RehashPassword(ctx context.Context, userID uuid.UUID, oldHash, newHash string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE users SET password_hash = $3 WHERE id = $1 AND password_hash = $2
+	`, userID, oldHash, newHash)
+	return err
+}
+
+// CreatePasswordReset сохраняет новый токен сброса; ранее выданные неиспользованные токены гасятся.
+func (d *DBStore) CreatePasswordReset(ctx context.Context, userID uuid.UUID, hash string, ttl time.Duration) (time.Time, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE password_resets SET used_at = now() WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var expiresAt time.Time
+	err = tx.QueryRow(ctx, `
+		INSERT INTO password_resets (token_hash, user_id, expires_at)
+		VALUES ($1, $2, now() + make_interval(secs => $3))
+		RETURNING expires_at
+	`, hash, userID, ttl.Seconds()).Scan(&expiresAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return time.Time{}, err
+	}
+	return expiresAt, nil
+}
+
+// ConsumePasswordReset одним действием гасит токен и устанавливает новый пароль.
+func (d *DBStore) ConsumePasswordReset(ctx context.Context, hash, passwordHash string) (uuid.UUID, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		UPDATE password_resets SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id
+	`, hash).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, customerrors.ErrInvalidResetToken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, passwordHash); err != nil {
+		return uuid.Nil, err
+	}
+	if err := writeAudit(ctx, tx, models.AuditEvent{Action: models.AuditPasswordReset, UserID: &userID}); err != nil {
+		return uuid.Nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
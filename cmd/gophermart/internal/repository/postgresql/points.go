@@ -0,0 +1,159 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func addPointLot(ctx context.Context, tx pgx.Tx, p models.LedgerPosting) error {
+	var orderNumber *string
+	if p.OrderNumber != "" {
+		orderNumber = &p.OrderNumber
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO point_lots (user_id, entry_type, order_number, adjustment_id, amount, remaining, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $5, CASE WHEN $6::int > 0 THEN now() + make_interval(months => $6::int) END)
+	`, p.UserID, p.EntryType, orderNumber, p.AdjustmentID, p.Amount, p.ExpiryMonths)
+	return err
+}
+
+// consumePointLots гасит amount баллов, начиная с самых ранних непросроченных партий, и возвращает
+// погашенную сумму. Если партий не хватает, возвращает ErrPointLotsShortfall, чтобы транзакция откатилась.
+func consumePointLots(ctx context.Context, tx pgx.Tx, userID uuid.UUID, amount models.Points) (models.Points, error) {
+	var consumed models.Points
+	err := tx.QueryRow(ctx, `
+		WITH locked AS (
+			SELECT id, remaining FROM point_lots
+			WHERE user_id = $1 AND remaining > 0 AND (expires_at IS NULL OR expires_at > now())
+			ORDER BY id
+			FOR UPDATE
+		), open AS (
+			SELECT id, remaining, SUM(remaining) OVER (ORDER BY id) - remaining AS before
+			FROM locked
+		), updated AS (
+			UPDATE point_lots l SET remaining = l.remaining - LEAST(o.remaining, $2 - o.before)
+			FROM open o
+			WHERE l.id = o.id AND o.before < $2
+			RETURNING LEAST(o.remaining, $2 - o.before) AS used
+		)
+		SELECT COALESCE(SUM(used), 0) FROM updated
+	`, userID, amount).Scan(&consumed)
+	if err != nil {
+		return 0, err
+	}
+	if consumed < amount {
+		return consumed, fmt.Errorf("%w: %s of %s", customerrors.ErrPointLotsShortfall, consumed, amount)
+	}
+	return consumed, nil
+}
+
+// GetUsersWithExpiredPoints возвращает пользователей, у которых есть просроченные непогашенные партии.
+func (d *DBStore) GetUsersWithExpiredPoints(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT DISTINCT user_id FROM point_lots
+		WHERE remaining > 0 AND expires_at <= now()
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	return users, rows.Err()
+}
+
+// ExpirePoints гасит просроченные партии пользователя и списывает их остаток проводкой expiry.
+func (d *DBStore) ExpirePoints(ctx context.Context, userID uuid.UUID) (models.Points, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return 0, err
+	}
+	expired, err := expirePointLots(ctx, tx, userID)
+	if err != nil || expired == 0 {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// expirePointLots обнуляет просроченные партии пользователя и списывает их остаток проводкой
+// expiry в транзакции вызывающего кода. Строка пользователя к этому моменту уже должна быть
+// заблокирована, как в Withdraw, чтобы не было взаимных блокировок. Списания вызывают её перед
+// проверкой баланса: иначе до запуска фоновой задачи просроченные баллы можно было бы потратить.
+func expirePointLots(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (models.Points, error) {
+	var expired models.Points
+	err := tx.QueryRow(ctx, `
+		WITH locked AS (
+			SELECT id, remaining FROM point_lots
+			WHERE user_id = $1 AND remaining > 0 AND expires_at <= now()
+			FOR UPDATE
+		), updated AS (
+			UPDATE point_lots l SET remaining = 0
+			FROM locked
+			WHERE l.id = locked.id
+			RETURNING locked.remaining
+		)
+		SELECT COALESCE(SUM(remaining), 0) FROM updated
+	`, userID).Scan(&expired)
+	if err != nil || expired == 0 {
+		return 0, err
+	}
+
+	err = postLedger(ctx, tx, models.LedgerPosting{
+		UserID:         userID,
+		EntryType:      models.LedgerEntryExpiry,
+		Amount:         -expired,
+		CounterAccount: models.LedgerAccountExpired,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// GetUpcomingExpirations суммирует непогашенные партии по дням сгорания, начиная с ближайшего.
+func (d *DBStore) GetUpcomingExpirations(ctx context.Context, userID uuid.UUID, limit int) ([]models.PointsExpiration, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT to_char(expires_at, 'YYYY-MM-DD') AS day, SUM(remaining)
+		FROM point_lots
+		WHERE user_id = $1 AND remaining > 0 AND expires_at IS NOT NULL
+		GROUP BY day
+		ORDER BY day
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.PointsExpiration
+	for rows.Next() {
+		var e models.PointsExpiration
+		if err := rows.Scan(&e.Date, &e.Amount); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,227 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// CreateSession заводит сессию и первый refresh-токен для неё в одной транзакции.
+func (d *DBStore) CreateSession(ctx context.Context, info models.SessionInfo, hash string, ttl time.Duration) (*models.RefreshToken, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rec := models.RefreshToken{UserID: info.UserID, SessionID: uuid.New()}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO sessions (id, user_id, device, ip, user_agent) VALUES ($1, $2, $3, $4, $5)
+	`, rec.SessionID, info.UserID, info.Device, info.IP, info.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (token_hash, user_id, session_id, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		RETURNING expires_at
+	`, hash, info.UserID, rec.SessionID, ttl.Seconds()).Scan(&rec.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, audit.LoginEvent(info, rec.SessionID)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// RotateRefreshToken погашает токен oldHash и выпускает вместо него newHash в той же сессии.
+// Повторное предъявление уже погашенного токена означает его утечку: сессия отзывается целиком.
+func (d *DBStore) RotateRefreshToken(ctx context.Context, oldHash, newHash string, ttl time.Duration) (*models.RefreshToken, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var rec models.RefreshToken
+	var rotated, revoked, expired bool
+	err = tx.QueryRow(ctx, `
+		SELECT user_id, session_id, rotated_at IS NOT NULL, revoked_at IS NOT NULL, expires_at <= now()
+		FROM refresh_tokens WHERE token_hash = $1
+		FOR UPDATE
+	`, oldHash).Scan(&rec.UserID, &rec.SessionID, &rotated, &revoked, &expired)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, customerrors.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rotated {
+		if err := revokeSession(ctx, tx, rec.SessionID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, customerrors.ErrRefreshTokenReused
+	}
+	if revoked || expired {
+		return nil, customerrors.ErrInvalidRefreshToken
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET rotated_at = now() WHERE token_hash = $1`, oldHash); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (token_hash, user_id, session_id, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		RETURNING expires_at
+	`, newHash, rec.UserID, rec.SessionID, ttl.Seconds()).Scan(&rec.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE sessions SET last_seen_at = now() WHERE id = $1`, rec.SessionID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// TouchSession отмечает активность сессии и сообщает, не отозвана ли она.
+func (d *DBStore) TouchSession(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	tag, err := d.db.Exec(ctx, `
+		UPDATE sessions SET last_seen_at = now() WHERE id = $1 AND revoked_at IS NULL
+	`, sessionID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (d *DBStore) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT id, device, ip, user_agent, created_at, last_seen_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.Device, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+func (d *DBStore) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var owner uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT user_id FROM sessions WHERE id = $1 AND revoked_at IS NULL FOR UPDATE
+	`, sessionID).Scan(&owner)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && owner != userID) {
+		return customerrors.ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := revokeSession(ctx, tx, sessionID); err != nil {
+		return err
+	}
+	err = writeAudit(ctx, tx, models.AuditEvent{
+		Action: models.AuditSessionRevoked,
+		UserID: &userID,
+		Target: sessionID.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RevokeSessions отзывает все активные сессии пользователя, кроме except, и возвращает их идентификаторы.
+func (d *DBStore) RevokeSessions(ctx context.Context, userID, except uuid.UUID) ([]uuid.UUID, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE sessions SET revoked_at = now()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+		RETURNING id
+	`, userID, except)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = now()
+		WHERE user_id = $1 AND session_id <> $2 AND revoked_at IS NULL
+	`, userID, except)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		if err := writeAudit(ctx, tx, audit.SessionsRevokedEvent(userID, ids)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func revokeSession(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE sessions SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL
+	`, sessionID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = now() WHERE session_id = $1 AND revoked_at IS NULL
+	`, sessionID)
+	return err
+}
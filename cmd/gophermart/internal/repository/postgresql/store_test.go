@@ -0,0 +1,155 @@
+package postgresql_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/postgresql"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/postgresql/pgtest"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/repositorytest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.StoreRepositoryInterface {
+		return postgresql.NewDBStore(pgtest.NewDatabase(t))
+	})
+}
+
+// Списание, которое не покрывается партиями баллов, откатывается целиком, даже если
+// кешированный баланс его позволяет.
+func TestWithdrawRollsBackOnLotShortfall(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.NewDatabase(t)
+	store := postgresql.NewDBStore(pool)
+
+	user, err := store.CreateUser(ctx, "user", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertOrder(ctx, user.ID, "12345678903"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateOrderAccrual(ctx, "12345678903", models.OrderStatusNew, 10000, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE point_lots SET remaining = 4000 WHERE user_id = $1`, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Withdraw(ctx, user.ID, "2377225624", 5000); !errors.Is(err, customerrors.ErrPointLotsShortfall) {
+		t.Fatalf("Withdraw error = %v, want ErrPointLotsShortfall", err)
+	}
+	balance, err := store.GetUserBalance(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Current != 10000 || balance.Withdrawn != 0 {
+		t.Errorf("balance = %+v, want current 100, withdrawn 0", balance)
+	}
+	var remaining models.Points
+	if err := pool.QueryRow(ctx, `SELECT SUM(remaining) FROM point_lots WHERE user_id = $1`, user.ID).Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 4000 {
+		t.Errorf("lots remaining = %s, want 40", remaining)
+	}
+}
+
+// Просроченные баллы нельзя потратить и до запуска фоновой задачи: списание сначала
+// сжигает их, а уже потом сверяет баланс.
+func TestWithdrawSkipsExpiredPoints(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.NewDatabase(t)
+	store := postgresql.NewDBStore(pool)
+
+	user, err := store.CreateUser(ctx, "user", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for number, accrual := range map[string]models.Points{"12345678903": 10000, "79927398713": 5000} {
+		if err := store.InsertOrder(ctx, user.ID, number); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.UpdateOrderAccrual(ctx, number, models.OrderStatusNew, accrual, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = pool.Exec(ctx, `UPDATE point_lots SET expires_at = now() - interval '1 hour' WHERE order_number = '12345678903'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Withdraw(ctx, user.ID, "2377225624", 12000); !errors.Is(err, customerrors.ErrInsufficientBalance) {
+		t.Fatalf("Withdraw error = %v, want ErrInsufficientBalance", err)
+	}
+	if err := store.Withdraw(ctx, user.ID, "2377225624", 5000); err != nil {
+		t.Fatal(err)
+	}
+	balance, err := store.GetUserBalance(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Current != 0 || balance.Withdrawn != 5000 {
+		t.Errorf("balance = %+v, want current 0, withdrawn 50", balance)
+	}
+}
+
+// Записи журнала попадают в цепочку только после запечатывания; запечатанную запись
+// триггер править не даёт.
+func TestSealAuditChain(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.NewDatabase(t)
+	store := postgresql.NewDBStore(pool)
+
+	for _, login := range []string{"first", "second"} {
+		if _, err := store.CreateUser(ctx, login, "hash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	head, err := store.GetAuditChainHead(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Seq != 0 {
+		t.Fatalf("head before sealing = %+v, want empty chain", head)
+	}
+
+	n, err := store.SealAuditChain(ctx, 1)
+	if err != nil || n != 1 {
+		t.Fatalf("SealAuditChain = %d, %v, want 1", n, err)
+	}
+	n, err = store.SealAuditChain(ctx, 10)
+	if err != nil || n != 1 {
+		t.Fatalf("SealAuditChain = %d, %v, want 1", n, err)
+	}
+
+	head, err = store.GetAuditChainHead(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := store.GetAuditChain(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := audit.NewVerifier()
+	for _, e := range chain {
+		v.Add(e)
+	}
+	if res := v.Finish(head.EventID, head.Hash); !res.Valid || res.Checked != 2 {
+		t.Errorf("verification = %+v, want 2 valid records", res)
+	}
+
+	if _, err := pool.Exec(ctx, `UPDATE audit_events SET target = 'forged' WHERE id = $1`, head.EventID); err == nil {
+		t.Error("sealed audit record was updated")
+	}
+}
@@ -0,0 +1,71 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+// GetTransactions собирает ленту из начислений и сгораний по журналу проводок, withdrawals и одобренных
+// корректировок; время начисления — время проводки, а не загрузки заказа. Нарастающий баланс считается
+// оконной функцией по всей истории пользователя до применения фильтров, поэтому он верен на любой странице.
+func (d *DBStore) GetTransactions(ctx context.Context, userID uuid.UUID, filter models.TransactionFilter) ([]models.Transaction, error) {
+	var cursorAt *time.Time
+	var cursorKey string
+	if filter.Cursor != nil {
+		cursorAt = &filter.Cursor.At
+		cursorKey = filter.Cursor.Key
+	}
+
+	rows, err := d.db.Query(ctx, `
+		WITH feed AS (
+			SELECT 'accrual' AS type, order_number, '' AS reason, amount,
+				created_at AS occurred_at, 'o:' || order_number AS key
+			FROM ledger_entries
+			WHERE user_id = $1 AND account = 'user' AND entry_type = 'accrual'
+			UNION ALL
+			SELECT 'withdrawal', order_number, '', -amount,
+				processed_at, 'w:' || lpad(id::text, 12, '0')
+			FROM withdrawals
+			WHERE user_id = $1
+			UNION ALL
+			SELECT 'adjustment', '', reason, amount,
+				decided_at, 'a:' || id::text
+			FROM balance_adjustments
+			WHERE user_id = $1 AND status = 'approved'
+			UNION ALL
+			SELECT 'expiry', '', '', amount,
+				created_at, 'e:' || lpad(id::text, 12, '0')
+			FROM ledger_entries
+			WHERE user_id = $1 AND account = 'user' AND entry_type = 'expiry'
+		), history AS (
+			SELECT *, SUM(amount) OVER (ORDER BY occurred_at, key ROWS UNBOUNDED PRECEDING) AS balance
+			FROM feed
+		)
+		SELECT type, order_number, reason, amount, balance, occurred_at, key
+		FROM history
+		WHERE ($2::text[] IS NULL OR type = ANY($2))
+			AND ($3::timestamp IS NULL OR occurred_at >= $3)
+			AND ($4::timestamp IS NULL OR occurred_at < $4)
+			AND ($5::timestamp IS NULL OR (occurred_at, key) < ($5, $6::text))
+		ORDER BY occurred_at DESC, key DESC
+		LIMIT $7
+	`, userID, filter.Types, filter.From, filter.To, cursorAt, cursorKey, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Transaction
+	for rows.Next() {
+		var t models.Transaction
+		if err := rows.Scan(&t.Type, &t.Order, &t.Reason, &t.Amount, &t.Balance, &t.OccurredAt, &t.Key); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,142 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+func (d *DBStore) GetTOTP(ctx context.Context, userID uuid.UUID) (*models.TOTP, error) {
+	var t models.TOTP
+	err := d.db.QueryRow(ctx, `
+		SELECT secret, confirmed_at IS NOT NULL, last_step FROM user_totp WHERE user_id = $1
+	`, userID).Scan(&t.Secret, &t.Confirmed, &t.LastStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, customerrors.ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveTOTPSecret заменяет секрет неподтверждённой регистрации; подтверждённый второй фактор не трогает.
+func (d *DBStore) SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	tag, err := d.db.Exec(ctx, `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0, created_at = now()
+		WHERE user_totp.confirmed_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+// ConfirmTOTP включает второй фактор и заменяет коды восстановления.
+func (d *DBStore) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE user_totp SET confirmed_at = now(), last_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrTOTPAlreadyEnabled
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO recovery_codes (code_hash, user_id) VALUES ($1, $2)
+		`, hash, userID); err != nil {
+			return err
+		}
+	}
+	if err := writeAudit(ctx, tx, models.AuditEvent{Action: models.AuditTOTPEnabled, UserID: &userID}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep отмечает интервал использованным; false — код этого интервала уже принимался.
+func (d *DBStore) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	tag, err := d.db.Exec(ctx, `
+		UPDATE user_totp SET last_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (d *DBStore) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	tag, err := d.db.Exec(ctx, `
+		UPDATE recovery_codes SET used_at = now()
+		WHERE code_hash = $1 AND user_id = $2 AND used_at IS NULL
+	`, hash, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (d *DBStore) CreateLoginChallenge(ctx context.Context, challenge models.LoginChallenge, hash string, ttl time.Duration) error {
+	_, err := d.db.Exec(ctx, `
+		INSERT INTO login_challenges (token_hash, user_id, device, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+	`, hash, challenge.UserID, challenge.Device, ttl.Seconds())
+	return err
+}
+
+// GetLoginChallenge возвращает незавершённый и не просроченный вход, по которому
+// сделано меньше maxAttempts неудачных попыток.
+func (d *DBStore) GetLoginChallenge(ctx context.Context, hash string, maxAttempts int) (*models.LoginChallenge, error) {
+	var ch models.LoginChallenge
+	err := d.db.QueryRow(ctx, `
+		SELECT c.user_id, u.login, c.device
+		FROM login_challenges c JOIN users u ON u.id = c.user_id
+		WHERE c.token_hash = $1 AND c.used_at IS NULL AND c.expires_at > now() AND c.attempts < $2
+	`, hash, maxAttempts).Scan(&ch.UserID, &ch.Login, &ch.Device)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, customerrors.ErrInvalidLoginChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func (d *DBStore) FailLoginChallenge(ctx context.Context, hash string) error {
+	_, err := d.db.Exec(ctx, `UPDATE login_challenges SET attempts = attempts + 1 WHERE token_hash = $1`, hash)
+	return err
+}
+
+func (d *DBStore) ConsumeLoginChallenge(ctx context.Context, hash string) (bool, error) {
+	tag, err := d.db.Exec(ctx, `
+		UPDATE login_challenges SET used_at = now() WHERE token_hash = $1 AND used_at IS NULL
+	`, hash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
@@ -9,15 +10,87 @@ import (
 
 type StoreRepositoryInterface interface {
 	// Аутентификация
-	CreateUser(ctx context.Context, login, password string) (*models.User, error)
+	CreateUser(ctx context.Context, login, passwordHash string) (*models.User, error)
 	GetUserByLogin(ctx context.Context, login string) (*models.User, error)
+	GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error)
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	RehashPassword(ctx context.Context, userID uuid.UUID, oldHash, newHash string) error
+	CreatePasswordReset(ctx context.Context, userID uuid.UUID, hash string, ttl time.Duration) (time.Time, error)
+	ConsumePasswordReset(ctx context.Context, hash, passwordHash string) (uuid.UUID, error)
+
+	// Второй фактор (TOTP)
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*models.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error)
+	CreateLoginChallenge(ctx context.Context, challenge models.LoginChallenge, hash string, ttl time.Duration) error
+	GetLoginChallenge(ctx context.Context, hash string, maxAttempts int) (*models.LoginChallenge, error)
+	FailLoginChallenge(ctx context.Context, hash string) error
+	ConsumeLoginChallenge(ctx context.Context, hash string) (bool, error)
+
+	// Сессии и refresh-токены
+	CreateSession(ctx context.Context, info models.SessionInfo, hash string, ttl time.Duration) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldHash, newHash string, ttl time.Duration) (*models.RefreshToken, error)
+	TouchSession(ctx context.Context, sessionID uuid.UUID) (bool, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeSessions(ctx context.Context, userID, except uuid.UUID) ([]uuid.UUID, error)
+
+	// API-ключи
+	CreateAPIKey(ctx context.Context, key models.APIKey, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error
+	UseAPIKey(ctx context.Context, hash string) (*models.APIKey, error)
 
 	// Работа с заказами
 	InsertOrder(ctx context.Context, userID uuid.UUID, orderNumber string) error
-	UpdateOrderAccrual(ctx context.Context, orderNumber, status string, accrual float64) error
+	GetOrderStatus(ctx context.Context, orderNumber string) (string, error)
+	UpdateOrderStatus(ctx context.Context, orderNumber, from, to string) error
+	UpdateOrderAccrual(ctx context.Context, orderNumber, from string, accrual models.Points, expiryMonths int) error
 	GetPendingOrders(ctx context.Context) ([]string, error)
-	GetOrdersByUser(ctx context.Context, userID uuid.UUID) ([]models.Order, error)
-	Withdraw(ctx context.Context, userID uuid.UUID, order string, amount float64) error
-	GetWithdrawals(ctx context.Context, userID uuid.UUID) ([]models.Withdrawal, error)
+	GetOrdersByUser(ctx context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Order, error)
+	Withdraw(ctx context.Context, userID uuid.UUID, order string, amount models.Points) error
+	GetWithdrawals(ctx context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Withdrawal, error)
 	GetUserBalance(ctx context.Context, userID uuid.UUID) (*models.Balance, error)
+	GetTransactions(ctx context.Context, userID uuid.UUID, filter models.TransactionFilter) ([]models.Transaction, error)
+
+	// Idempotency-Key
+	BeginIdempotentRequest(ctx context.Context, userID uuid.UUID, key, fingerprint string, lease time.Duration) (*models.IdempotencyRecord, error)
+	CompleteIdempotentRequest(ctx context.Context, userID uuid.UUID, key string, statusCode int, body []byte) error
+	ReleaseIdempotentRequest(ctx context.Context, userID uuid.UUID, key string) error
+
+	// Журнал аудита. Изменения данных пишут свои события сами, в той же транзакции;
+	// WriteAuditEvent — для событий, которые в базе ничего не меняют.
+	WriteAuditEvent(ctx context.Context, event models.AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+	// SealAuditChain дописывает хеши ещё не запечатанных записей и возвращает их число.
+	SealAuditChain(ctx context.Context, limit int) (int, error)
+	GetAuditChain(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEvent, error)
+	GetAuditChainHead(ctx context.Context) (models.AuditChainHead, error)
+
+	// Администрирование
+	SetUserRole(ctx context.Context, userID uuid.UUID, role string) error
+	SetUserBlocked(ctx context.Context, userID uuid.UUID, blocked bool) error
+	RetriggerAccrualJob(ctx context.Context, orderNumber string) error
+
+	// Ручные корректировки баланса
+	CreateAdjustment(ctx context.Context, adj models.Adjustment) (*models.Adjustment, error)
+	ListAdjustments(ctx context.Context, filter models.AdjustmentFilter) ([]models.Adjustment, error)
+	ApproveAdjustment(ctx context.Context, adjustmentID, approverID uuid.UUID, expiryMonths int) (*models.Adjustment, error)
+	RejectAdjustment(ctx context.Context, adjustmentID, deciderID uuid.UUID) (*models.Adjustment, error)
+
+	// Сверка кешированных балансов с журналом проводок
+	ReconcileBalances(ctx context.Context) (*models.ReconciliationReport, error)
+
+	// Сгорание баллов
+	GetUsersWithExpiredPoints(ctx context.Context, limit int) ([]uuid.UUID, error)
+	ExpirePoints(ctx context.Context, userID uuid.UUID) (models.Points, error)
+	GetUpcomingExpirations(ctx context.Context, userID uuid.UUID, limit int) ([]models.PointsExpiration, error)
+
+	// Очередь опроса системы начислений
+	EnqueueAccrualJob(ctx context.Context, orderNumber string) (bool, error)
+	LeaseAccrualJobs(ctx context.Context, limit int, lease time.Duration) ([]models.AccrualJob, error)
+	CompleteAccrualJob(ctx context.Context, orderNumber string) error
+	RescheduleAccrualJob(ctx context.Context, orderNumber string, delay time.Duration, lastErr string) error
 }
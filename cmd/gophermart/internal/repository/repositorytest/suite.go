@@ -0,0 +1,323 @@
+// Package repositorytest — общий набор проверок, который должна проходить каждая
+// реализация repository.StoreRepositoryInterface.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// NewStore возвращает пустое хранилище для одного теста.
+type NewStore func(t *testing.T) repository.StoreRepositoryInterface
+
+// Run прогоняет набор проверок; newStore вызывается заново для каждой из них.
+func Run(t *testing.T, newStore NewStore) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store repository.StoreRepositoryInterface)
+	}{
+		{"DuplicateOrders", testDuplicateOrders},
+		{"InsufficientBalance", testInsufficientBalance},
+		{"DuplicateWithdrawalOrder", testDuplicateWithdrawalOrder},
+		{"OrdersSortOrder", testOrdersSortOrder},
+		{"WithdrawalsSortOrder", testWithdrawalsSortOrder},
+		{"TransactionsUseCreditTime", testTransactionsUseCreditTime},
+		{"StaleIdempotencyKeyTakeover", testStaleIdempotencyKeyTakeover},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, store repository.StoreRepositoryInterface, login string) uuid.UUID {
+	t.Helper()
+	user, err := store.CreateUser(context.Background(), login, "hash")
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", login, err)
+	}
+	return user.ID
+}
+
+// credit начисляет баллы через обработанный заказ.
+func credit(t *testing.T, store repository.StoreRepositoryInterface, userID uuid.UUID, order string, amount models.Points) {
+	t.Helper()
+	ctx := context.Background()
+	if err := store.InsertOrder(ctx, userID, order); err != nil {
+		t.Fatalf("InsertOrder(%s): %v", order, err)
+	}
+	if err := store.UpdateOrderAccrual(ctx, order, models.OrderStatusNew, amount, 0); err != nil {
+		t.Fatalf("UpdateOrderAccrual(%s): %v", order, err)
+	}
+}
+
+// tick разводит соседние записи по времени, чтобы порядок выдачи не зависел от вторичного ключа.
+func tick() {
+	time.Sleep(2 * time.Millisecond)
+}
+
+func testDuplicateOrders(t *testing.T, store repository.StoreRepositoryInterface) {
+	ctx := context.Background()
+	owner := createUser(t, store, "owner")
+	other := createUser(t, store, "other")
+
+	if err := store.InsertOrder(ctx, owner, "12345678903"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertOrder(ctx, owner, "12345678903"); !errors.Is(err, customerrors.ErrOrderAlreadyUploadedBySameUser) {
+		t.Errorf("same user: error = %v, want ErrOrderAlreadyUploadedBySameUser", err)
+	}
+	if err := store.InsertOrder(ctx, other, "12345678903"); !errors.Is(err, customerrors.ErrOrderUploadedByAnotherUser) {
+		t.Errorf("another user: error = %v, want ErrOrderUploadedByAnotherUser", err)
+	}
+
+	orders, err := store.GetOrdersByUser(ctx, other, models.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 0 {
+		t.Errorf("rejected order is listed for another user: %+v", orders)
+	}
+}
+
+func testInsufficientBalance(t *testing.T, store repository.StoreRepositoryInterface) {
+	ctx := context.Background()
+	userID := createUser(t, store, "user")
+	credit(t, store, userID, "12345678903", 10000)
+
+	if err := store.Withdraw(ctx, userID, "2377225624", 10001); !errors.Is(err, customerrors.ErrInsufficientBalance) {
+		t.Fatalf("error = %v, want ErrInsufficientBalance", err)
+	}
+	if err := store.Withdraw(ctx, userID, "2377225624", 10000); err != nil {
+		t.Fatalf("withdrawing the whole balance: %v", err)
+	}
+	if err := store.Withdraw(ctx, userID, "2377225608", 1); !errors.Is(err, customerrors.ErrInsufficientBalance) {
+		t.Fatalf("error = %v, want ErrInsufficientBalance on empty balance", err)
+	}
+
+	balance, err := store.GetUserBalance(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Current != 0 || balance.Withdrawn != 10000 {
+		t.Errorf("balance = %+v, want current 0, withdrawn 100", balance)
+	}
+	list, err := store.GetWithdrawals(ctx, userID, models.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Errorf("failed withdrawals must not be stored: %+v", list)
+	}
+}
+
+func testDuplicateWithdrawalOrder(t *testing.T, store repository.StoreRepositoryInterface) {
+	ctx := context.Background()
+	userID := createUser(t, store, "user")
+	credit(t, store, userID, "12345678903", 10000)
+
+	if err := store.Withdraw(ctx, userID, "2377225624", 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Withdraw(ctx, userID, "2377225624", 1000); !errors.Is(err, customerrors.ErrWithdrawalOrderExists) {
+		t.Errorf("error = %v, want ErrWithdrawalOrderExists", err)
+	}
+	// повтор номера проверяется раньше баланса
+	if err := store.Withdraw(ctx, userID, "2377225624", 100000); !errors.Is(err, customerrors.ErrWithdrawalOrderExists) {
+		t.Errorf("above balance: error = %v, want ErrWithdrawalOrderExists", err)
+	}
+	other := createUser(t, store, "other")
+	if err := store.Withdraw(ctx, other, "2377225624", 1000); !errors.Is(err, customerrors.ErrWithdrawalOrderExists) {
+		t.Errorf("another user with empty balance: error = %v, want ErrWithdrawalOrderExists", err)
+	}
+
+	balance, err := store.GetUserBalance(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Current != 9000 {
+		t.Errorf("balance = %s, want 90", balance.Current)
+	}
+}
+
+func testOrdersSortOrder(t *testing.T, store repository.StoreRepositoryInterface) {
+	ctx := context.Background()
+	userID := createUser(t, store, "user")
+	numbers := []string{"2377225608", "12345678903", "2377225616"}
+	for _, n := range numbers {
+		if err := store.InsertOrder(ctx, userID, n); err != nil {
+			t.Fatal(err)
+		}
+		tick()
+	}
+
+	asc, err := store.GetOrdersByUser(ctx, userID, models.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertOrder(t, "ascending", orderNumbers(asc), numbers)
+
+	desc, err := store.GetOrdersByUser(ctx, userID, models.ListFilter{Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertOrder(t, "descending", orderNumbers(desc), []string{numbers[2], numbers[1], numbers[0]})
+
+	// страница после курсора продолжает выдачу с того же места
+	first, err := store.GetOrdersByUser(ctx, userID, models.ListFilter{Desc: true, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := first[len(first)-1]
+	rest, err := store.GetOrdersByUser(ctx, userID, models.ListFilter{
+		Desc:   true,
+		Cursor: &models.Cursor{At: last.UploadedAt, Key: last.Number},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertOrder(t, "pages", append(orderNumbers(first), orderNumbers(rest)...), orderNumbers(desc))
+}
+
+func testWithdrawalsSortOrder(t *testing.T, store repository.StoreRepositoryInterface) {
+	ctx := context.Background()
+	userID := createUser(t, store, "user")
+	credit(t, store, userID, "12345678903", 10000)
+	numbers := []string{"2377225624", "2377225608", "2377225632"}
+	for _, n := range numbers {
+		if err := store.Withdraw(ctx, userID, n, 100); err != nil {
+			t.Fatal(err)
+		}
+		tick()
+	}
+
+	asc, err := store.GetWithdrawals(ctx, userID, models.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertOrder(t, "ascending", withdrawalOrders(asc), numbers)
+
+	desc, err := store.GetWithdrawals(ctx, userID, models.ListFilter{Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertOrder(t, "descending", withdrawalOrders(desc), []string{numbers[2], numbers[1], numbers[0]})
+}
+
+// testTransactionsUseCreditTime: начисление попадает в ленту со временем проводки, поэтому
+// заказ, загруженный раньше списания, но обработанный позже, идёт в ленте после списания.
+func testTransactionsUseCreditTime(t *testing.T, store repository.StoreRepositoryInterface) {
+	ctx := context.Background()
+	userID := createUser(t, store, "user")
+	credit(t, store, userID, "12345678903", 10000)
+	tick()
+	if err := store.InsertOrder(ctx, userID, "2377225608"); err != nil {
+		t.Fatal(err)
+	}
+	tick()
+	if err := store.Withdraw(ctx, userID, "2377225624", 10000); err != nil {
+		t.Fatal(err)
+	}
+	tick()
+	if err := store.UpdateOrderAccrual(ctx, "2377225608", models.OrderStatusNew, 500, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err := store.GetTransactions(ctx, userID, models.TransactionFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, tx := range feed {
+		got = append(got, tx.Type+":"+tx.Order+":"+tx.Balance.String())
+	}
+	assertOrder(t, "feed", got, []string{
+		"accrual:2377225608:5",
+		"withdrawal:2377225624:0",
+		"accrual:12345678903:100",
+	})
+}
+
+func testStaleIdempotencyKeyTakeover(t *testing.T, store repository.StoreRepositoryInterface) {
+	ctx := context.Background()
+	userID := createUser(t, store, "user")
+	const lease = 10 * time.Millisecond
+
+	if rec, err := store.BeginIdempotentRequest(ctx, userID, "key", "body", lease); err != nil || rec != nil {
+		t.Fatalf("first begin = %+v, %v; want nil, nil", rec, err)
+	}
+	rec, err := store.BeginIdempotentRequest(ctx, userID, "key", "body", lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec == nil || rec.StatusCode != 0 {
+		t.Fatalf("begin within lease = %+v, want in-progress record", rec)
+	}
+
+	time.Sleep(3 * lease)
+	// другое тело не перехватывает ключ даже после истечения аренды
+	rec, err = store.BeginIdempotentRequest(ctx, userID, "key", "other body", lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec == nil || rec.Fingerprint != "body" {
+		t.Fatalf("begin with another body = %+v, want original record", rec)
+	}
+	if rec, err = store.BeginIdempotentRequest(ctx, userID, "key", "body", time.Minute); err != nil || rec != nil {
+		t.Fatalf("begin after lease = %+v, %v; want takeover", rec, err)
+	}
+	if rec, err = store.BeginIdempotentRequest(ctx, userID, "key", "body", time.Minute); err != nil || rec == nil {
+		t.Fatalf("begin after takeover = %+v, %v; want in-progress record", rec, err)
+	}
+
+	if err := store.CompleteIdempotentRequest(ctx, userID, "key", 200, []byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(3 * lease)
+	rec, err = store.BeginIdempotentRequest(ctx, userID, "key", "body", lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec == nil || rec.StatusCode != 200 || string(rec.Body) != "ok" {
+		t.Errorf("begin after complete = %+v, want stored response", rec)
+	}
+}
+
+func orderNumbers(orders []models.Order) []string {
+	res := make([]string, 0, len(orders))
+	for _, o := range orders {
+		res = append(res, o.Number)
+	}
+	return res
+}
+
+func withdrawalOrders(list []models.Withdrawal) []string {
+	res := make([]string, 0, len(list))
+	for _, w := range list {
+		res = append(res, w.Order)
+	}
+	return res
+}
+
+func assertOrder(t *testing.T, name string, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got %v, want %v", name, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+			return
+		}
+	}
+}
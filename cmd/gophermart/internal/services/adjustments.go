@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+const maxAdjustmentCommentLength = 500
+
+// ProposeAdjustment создаёт ожидающую одобрения корректировку баланса пользователя.
+func (s *Service) ProposeAdjustment(ctx context.Context, actorID, userID string, req models.AdjustmentRequest) (*models.Adjustment, error) {
+	proposer, err := uuid.Parse(actorID)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, customerrors.ErrUserNotFound
+	}
+	amount, err := adjustmentAmount(req)
+	if err != nil {
+		return nil, err
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if len(comment) > maxAdjustmentCommentLength {
+		return nil, fmt.Errorf("%w: comment is longer than %d characters", customerrors.ErrInvalidAdjustment, maxAdjustmentCommentLength)
+	}
+	if !isKnownAdjustmentReason(req.Reason) {
+		return nil, fmt.Errorf("%w: unknown reason %q", customerrors.ErrInvalidAdjustment, req.Reason)
+	}
+	if req.Reason == models.AdjustmentReasonOther && comment == "" {
+		return nil, fmt.Errorf("%w: comment is required for reason %q", customerrors.ErrInvalidAdjustment, req.Reason)
+	}
+	if _, err := s.repo.GetUserByID(ctx, uid); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateAdjustment(ctx, models.Adjustment{
+		ID:         uuid.New(),
+		UserID:     uid,
+		Amount:     amount,
+		Reason:     req.Reason,
+		Comment:    comment,
+		ProposedBy: proposer,
+	})
+}
+
+func (s *Service) ListAdjustments(ctx context.Context, filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	filter.Limit = normalizeLimit(filter.Limit)
+	return s.repo.ListAdjustments(ctx, filter)
+}
+
+// ApproveAdjustment применяет корректировку; одобрить её может только другой администратор.
+func (s *Service) ApproveAdjustment(ctx context.Context, actorID, adjustmentID string) (*models.Adjustment, error) {
+	approver, err := uuid.Parse(actorID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(adjustmentID)
+	if err != nil {
+		return nil, customerrors.ErrAdjustmentNotFound
+	}
+	return s.repo.ApproveAdjustment(ctx, id, approver, s.points.ExpiryMonths)
+}
+
+func (s *Service) RejectAdjustment(ctx context.Context, actorID, adjustmentID string) (*models.Adjustment, error) {
+	decider, err := uuid.Parse(actorID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(adjustmentID)
+	if err != nil {
+		return nil, customerrors.ErrAdjustmentNotFound
+	}
+	return s.repo.RejectAdjustment(ctx, id, decider)
+}
+
+// adjustmentAmount переводит тип корректировки в знак суммы: списание отрицательно.
+func adjustmentAmount(req models.AdjustmentRequest) (models.Points, error) {
+	if !req.Amount.IsPositive() {
+		return 0, fmt.Errorf("%w: amount must be positive", customerrors.ErrInvalidAdjustment)
+	}
+	switch req.Type {
+	case models.AdjustmentCredit:
+		return req.Amount, nil
+	case models.AdjustmentDebit:
+		return -req.Amount, nil
+	}
+	return 0, fmt.Errorf("%w: type must be %q or %q", customerrors.ErrInvalidAdjustment, models.AdjustmentCredit, models.AdjustmentDebit)
+}
+
+func isKnownAdjustmentReason(reason string) bool {
+	for _, r := range models.AdjustmentReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+// UserRole возвращает роль пользователя для проверки прав на административные эндпоинты.
+func (s *Service) UserRole(ctx context.Context, userID string) (string, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return "", err
+	}
+	user, err := s.repo.GetUserByID(ctx, uid)
+	if err != nil {
+		return "", err
+	}
+	if user.Blocked {
+		return "", customerrors.ErrUserBlocked
+	}
+	return user.Role, nil
+}
+
+func (s *Service) GetUserInfo(ctx context.Context, userID string) (*models.UserInfo, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, customerrors.ErrUserNotFound
+	}
+	user, err := s.repo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return userInfo(user), nil
+}
+
+func (s *Service) FindUser(ctx context.Context, login string) (*models.UserInfo, error) {
+	user, err := s.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	return userInfo(user), nil
+}
+
+func userInfo(u *models.User) *models.UserInfo {
+	return &models.UserInfo{ID: u.ID, Login: u.Login, Role: u.Role, Blocked: u.Blocked}
+}
+
+// SetUserRole меняет роль пользователя; свою роль администратор поменять не может,
+// чтобы не остаться без администраторов по ошибке.
+func (s *Service) SetUserRole(ctx context.Context, actorID, userID, role string) error {
+	if !isKnownRole(role) {
+		return customerrors.ErrInvalidRole
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return customerrors.ErrUserNotFound
+	}
+	if userID == actorID {
+		return customerrors.ErrSelfModification
+	}
+	return s.repo.SetUserRole(ctx, uid, role)
+}
+
+// SetUserBlocked блокирует или разблокирует учётную запись. Блокировка сразу отзывает все сессии,
+// а API-ключи заблокированного пользователя перестают приниматься.
+func (s *Service) SetUserBlocked(ctx context.Context, actorID, userID string, blocked bool) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return customerrors.ErrUserNotFound
+	}
+	if userID == actorID {
+		return customerrors.ErrSelfModification
+	}
+	if err := s.repo.SetUserBlocked(ctx, uid, blocked); err != nil {
+		return err
+	}
+	if blocked {
+		revoked, err := s.repo.RevokeSessions(ctx, uid, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		s.sessions.forget(revoked...)
+	}
+	return nil
+}
+
+// RetriggerAccrual немедленно возобновляет опрос системы начислений по незавершённому заказу.
+func (s *Service) RetriggerAccrual(ctx context.Context, orderNumber string) error {
+	status, err := s.repo.GetOrderStatus(ctx, orderNumber)
+	if err != nil {
+		return err
+	}
+	if IsFinalOrderStatus(status) {
+		return customerrors.ErrOrderAlreadyFinal
+	}
+	return s.repo.RetriggerAccrualJob(ctx, orderNumber)
+}
+
+// EnsureAdmins назначает роль admin уже зарегистрированным пользователям из списка;
+// несуществующие логины пропускаются, чтобы их не мог занять кто-то другой.
+func (s *Service) EnsureAdmins(ctx context.Context, logins []string) error {
+	for _, login := range logins {
+		user, err := s.repo.GetUserByLogin(ctx, login)
+		if errors.Is(err, customerrors.ErrUserNotFound) {
+			log.Printf("admin login %q is not registered, skipping", login)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if user.Role == models.RoleAdmin {
+			continue
+		}
+		if err := s.repo.SetUserRole(ctx, user.ID, models.RoleAdmin); err != nil {
+			return err
+		}
+		log.Printf("granted admin role to %s", login)
+	}
+	return nil
+}
+
+func isKnownRole(role string) bool {
+	switch role {
+	case models.RoleUser, models.RoleSupport, models.RoleAdmin:
+		return true
+	}
+	return false
+}
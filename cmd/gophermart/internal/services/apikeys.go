@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+const maxAPIKeyNameLength = 100
+
+// CreateAPIKey выпускает ключ с указанными областями действия; сам ключ возвращается
+// только здесь, в базе остаётся хеш.
+func (s *Service) CreateAPIKey(ctx context.Context, userID string, req models.APIKeyRequest) (*models.NewAPIKey, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return nil, fmt.Errorf("%w: name must be 1-%d characters", customerrors.ErrInvalidAPIKeyRequest, maxAPIKeyNameLength)
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateAPIKey(ctx, models.APIKey{
+		ID:     uuid.New(),
+		UserID: uid,
+		Name:   name,
+		Prefix: prefix,
+		Scopes: scopes,
+	}, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &models.NewAPIKey{APIKey: *created, Key: key}, nil
+}
+
+func (s *Service) ListAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListAPIKeys(ctx, uid)
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	kid, err := uuid.Parse(keyID)
+	if err != nil {
+		return customerrors.ErrAPIKeyNotFound
+	}
+	return s.repo.RevokeAPIKey(ctx, uid, kid)
+}
+
+// AuthenticateAPIKey возвращает действующий ключ; неизвестный или отозванный — ErrInvalidAPIKey.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, auth.APIKeyPrefix) {
+		return nil, customerrors.ErrInvalidAPIKey
+	}
+	return s.repo.UseAPIKey(ctx, auth.HashToken(key))
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", customerrors.ErrInvalidAPIKeyRequest)
+	}
+	seen := make(map[string]bool, len(scopes))
+	res := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !isKnownScope(scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", customerrors.ErrInvalidAPIKeyRequest, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			res = append(res, scope)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func isKnownScope(scope string) bool {
+	for _, known := range models.APIKeyScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
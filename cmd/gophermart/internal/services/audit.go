@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/audit"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+const (
+	auditVerifyBatch = 1000
+	auditSealBatch   = 500
+)
+
+// ListAuditEvents возвращает страницу журнала аудита от новых записей к старым и курсор следующей.
+func (s *Service) ListAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, string, error) {
+	limit := normalizeLimit(filter.Limit)
+	filter.Limit = limit + 1
+
+	list, err := s.repo.ListAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(list) <= limit {
+		return list, "", nil
+	}
+	list = list[:limit]
+	last := list[limit-1]
+	return list, models.Cursor{At: last.CreatedAt, Key: strconv.FormatInt(last.ID, 10)}.Encode(), nil
+}
+
+// SealAuditChain запечатывает все записи журнала, зафиксированные к этому моменту, порциями
+// по auditSealBatch и возвращает их число.
+func (s *Service) SealAuditChain(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := s.repo.SealAuditChain(ctx, auditSealBatch)
+		total += n
+		if err != nil || n < auditSealBatch {
+			return total, err
+		}
+	}
+}
+
+// VerifyAuditChain пересчитывает хеши всех записей цепочки до текущей головы. Записи,
+// запечатанные во время проверки, и ещё не запечатанные в неё не попадают.
+func (s *Service) VerifyAuditChain(ctx context.Context) (*models.AuditVerification, error) {
+	head, err := s.repo.GetAuditChainHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v := audit.NewVerifier()
+	var seq int64
+scan:
+	for seq < head.Seq {
+		// номера в цепочке идут подряд, поэтому следующая страница начинается после прочитанных
+		batch, err := s.repo.GetAuditChain(ctx, seq, int(min(head.Seq-seq, auditVerifyBatch)))
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, e := range batch {
+			if !v.Add(e) {
+				break scan
+			}
+		}
+		seq += int64(len(batch))
+	}
+	res := v.Finish(head.EventID, head.Hash)
+	return &res, nil
+}
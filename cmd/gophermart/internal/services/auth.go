@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+type AuthConfig struct {
+	Signer          *auth.Signer
+	RefreshTTL      time.Duration
+	SessionCacheTTL time.Duration
+	Attempts        AttemptStore
+	Throttle        ThrottleConfig
+	PasswordPolicy  PasswordPolicy
+	BcryptCost      int
+	Notifier        Notifier
+	// списания больше порога требуют кода второго фактора; 0 — не требуют
+	WithdrawOTPThreshold models.Points
+}
+
+// IssueTokens открывает новую сессию для устройства, с которого выполнен вход.
+func (s *Service) IssueTokens(ctx context.Context, info models.SessionInfo) (*models.TokenPair, error) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	rec, err := s.repo.CreateSession(ctx, info, hash, s.auth.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokenPair(rec, token)
+}
+
+// RefreshTokens обменивает refresh-токен на новую пару; старый токен больше не принимается.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	rec, err := s.repo.RotateRefreshToken(ctx, auth.HashToken(refreshToken), hash, s.auth.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokenPair(rec, token)
+}
+
+func (s *Service) Logout(ctx context.Context, userID, sessionID string) error {
+	return s.RevokeSession(ctx, userID, sessionID)
+}
+
+// IsSessionActive проверяет, что сессия из access-токена не отозвана.
+func (s *Service) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	sid, err := uuid.Parse(sessionID)
+	if err != nil {
+		return false, nil
+	}
+	now := time.Now()
+	if s.sessions.alive(sid, now) {
+		return true, nil
+	}
+	active, err := s.repo.TouchSession(ctx, sid)
+	if err != nil {
+		return false, err
+	}
+	if active {
+		s.sessions.remember(sid, now)
+	}
+	return active, nil
+}
+
+// ListSessions возвращает активные сессии пользователя, помечая ту, из которой пришёл запрос.
+func (s *Service) ListSessions(ctx context.Context, userID, currentSessionID string) ([]models.Session, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.repo.ListSessions(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID.String() == currentSessionID
+	}
+	return sessions, nil
+}
+
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	sid, err := uuid.Parse(sessionID)
+	if err != nil {
+		return customerrors.ErrSessionNotFound
+	}
+	if err := s.repo.RevokeSession(ctx, uid, sid); err != nil {
+		return err
+	}
+	s.sessions.forget(sid)
+	return nil
+}
+
+// RevokeOtherSessions отзывает все сессии пользователя, кроме keepSessionID; пустой keepSessionID — все.
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) (int, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, err
+	}
+	keep := uuid.Nil
+	if keepSessionID != "" {
+		if keep, err = uuid.Parse(keepSessionID); err != nil {
+			return 0, err
+		}
+	}
+	revoked, err := s.repo.RevokeSessions(ctx, uid, keep)
+	if err != nil {
+		return 0, err
+	}
+	s.sessions.forget(revoked...)
+	return len(revoked), nil
+}
+
+func (s *Service) tokenPair(rec *models.RefreshToken, refreshToken string) (*models.TokenPair, error) {
+	access, expiresAt, err := s.auth.Signer.Issue(rec.UserID.String(), rec.SessionID.String())
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenPair{
+		AccessToken:      access,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(s.auth.Signer.TTL().Seconds()),
+		RefreshToken:     refreshToken,
+		AccessExpiresAt:  expiresAt,
+		RefreshExpiresAt: rec.ExpiresAt,
+	}, nil
+}
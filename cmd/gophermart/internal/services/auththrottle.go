@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+// AttemptStore считает неудачные попытки входа по ключу и хранит блокировки.
+// Реализации: repository/memory (по умолчанию) и repository/postgresql (общая для реплик).
+type AttemptStore interface {
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Block(ctx context.Context, key string, d time.Duration) error
+	Reset(ctx context.Context, key string) error
+}
+
+// ThrottleConfig: первые FreeAttempts неудач в окне Window бесплатны, дальше каждая следующая
+// попытка откладывается на BaseDelay, 2*BaseDelay, ... (не больше MaxDelay), а по достижении
+// порога ключ блокируется на LockoutFor.
+type ThrottleConfig struct {
+	Window         time.Duration
+	FreeAttempts   int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	LoginThreshold int
+	IPThreshold    int
+	LockoutFor     time.Duration
+}
+
+func DefaultThrottleConfig() ThrottleConfig {
+	return ThrottleConfig{
+		Window:         15 * time.Minute,
+		FreeAttempts:   3,
+		BaseDelay:      time.Second,
+		MaxDelay:       30 * time.Second,
+		LoginThreshold: 10,
+		IPThreshold:    50,
+		LockoutFor:     15 * time.Minute,
+	}
+}
+
+type throttleKey struct {
+	key       string
+	threshold int
+}
+
+func authThrottleKeys(cfg ThrottleConfig, login, ip string) []throttleKey {
+	var keys []throttleKey
+	if login != "" {
+		keys = append(keys, throttleKey{key: "login:" + login, threshold: cfg.LoginThreshold})
+	}
+	if ip != "" {
+		keys = append(keys, throttleKey{key: "ip:" + ip, threshold: cfg.IPThreshold})
+	}
+	return keys
+}
+
+// CheckAuthAttempt возвращает, сколько нужно подождать до следующей попытки входа
+// для этого логина и адреса; 0 — попытка разрешена. Пустой login проверяет только адрес.
+func (s *Service) CheckAuthAttempt(ctx context.Context, login, ip string) (time.Duration, error) {
+	var wait time.Duration
+	for _, k := range authThrottleKeys(s.auth.Throttle, login, ip) {
+		d, err := s.auth.Attempts.BlockedFor(ctx, k.key)
+		if err != nil {
+			return 0, err
+		}
+		if d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// RecordAuthFailure учитывает неудачную попытку и при необходимости откладывает
+// или блокирует следующие; блокировки пишутся в журнал аудита.
+func (s *Service) RecordAuthFailure(ctx context.Context, login, ip string) error {
+	cfg := s.auth.Throttle
+	for _, k := range authThrottleKeys(cfg, login, ip) {
+		failures, err := s.auth.Attempts.RecordFailure(ctx, k.key, cfg.Window)
+		if err != nil {
+			return err
+		}
+
+		if failures >= k.threshold {
+			if err := s.auth.Attempts.Block(ctx, k.key, cfg.LockoutFor); err != nil {
+				return err
+			}
+			log.Printf("auth lockout: %s after %d failures", k.key, failures)
+			err = s.repo.WriteAuditEvent(ctx, models.AuditEvent{
+				Action: models.AuditAuthLockout,
+				Target: k.key,
+				IP:     ip,
+				Details: map[string]interface{}{
+					"failures":   failures,
+					"locked_for": cfg.LockoutFor.String(),
+					"login":      login,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if delay := throttleDelay(cfg, failures); delay > 0 {
+			if err := s.auth.Attempts.Block(ctx, k.key, delay); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RecordAuthSuccess сбрасывает счётчик логина; счётчик адреса живёт до конца окна.
+func (s *Service) RecordAuthSuccess(ctx context.Context, login string) error {
+	return s.auth.Attempts.Reset(ctx, "login:"+login)
+}
+
+func throttleDelay(cfg ThrottleConfig, failures int) time.Duration {
+	if failures <= cfg.FreeAttempts {
+		return 0
+	}
+	delay := cfg.BaseDelay
+	for i := cfg.FreeAttempts + 1; i < failures && delay < cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > cfg.MaxDelay {
+		delay = cfg.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,36 @@
+package services
+
+import "github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+
+// NEW → PROCESSING → PROCESSED/INVALID; из финальных статусов переходов нет.
+var orderTransitions = map[string][]string{
+	models.OrderStatusNew:        {models.OrderStatusProcessing, models.OrderStatusProcessed, models.OrderStatusInvalid},
+	models.OrderStatusProcessing: {models.OrderStatusProcessed, models.OrderStatusInvalid},
+}
+
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func IsFinalOrderStatus(status string) bool {
+	return status == models.OrderStatusProcessed || status == models.OrderStatusInvalid
+}
+
+// orderStatusFromAccrual переводит статус системы начислений в статус заказа.
+func orderStatusFromAccrual(accrualStatus string) (string, bool) {
+	switch accrualStatus {
+	case models.AccrualStatusRegistered, models.AccrualStatusProcessing:
+		return models.OrderStatusProcessing, true
+	case models.AccrualStatusInvalid:
+		return models.OrderStatusInvalid, true
+	case models.AccrualStatusProcessed:
+		return models.OrderStatusProcessed, true
+	default:
+		return "", false
+	}
+}
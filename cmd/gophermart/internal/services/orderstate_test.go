@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/accrual"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/memory"
+)
+
+const testOrder = "12345678903"
+
+func newTestService(t *testing.T) (*Service, *memory.MemoryStore, *accrual.FakeClient, uuid.UUID) {
+	t.Helper()
+	store := memory.NewMemoryStore()
+	fake := accrual.NewFakeClient()
+	svc := NewService(store, fake, NewRateLimiter(0, 1), AuthConfig{}, PointsConfig{})
+
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "user", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertOrder(ctx, user.ID, testOrder); err != nil {
+		t.Fatal(err)
+	}
+	return svc, store, fake, user.ID
+}
+
+func accrualOf(v string) *json.Number {
+	n := json.Number(v)
+	return &n
+}
+
+func TestPollAccrualResponses(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     []models.AccrualResponse
+		response   models.AccrualResponse
+		wantStatus string
+		wantDelay  bool
+		wantErr    bool
+		wantAmount models.Points
+	}{
+		{
+			name:       "registered",
+			response:   models.AccrualResponse{Status: models.AccrualStatusRegistered},
+			wantStatus: models.OrderStatusProcessing,
+			wantDelay:  true,
+		},
+		{
+			name:       "processing",
+			response:   models.AccrualResponse{Status: models.AccrualStatusProcessing},
+			wantStatus: models.OrderStatusProcessing,
+			wantDelay:  true,
+		},
+		{
+			name:       "processed with accrual",
+			response:   models.AccrualResponse{Status: models.AccrualStatusProcessed, Accrual: accrualOf("729.98")},
+			wantStatus: models.OrderStatusProcessed,
+			wantAmount: 72998,
+		},
+		{
+			name:       "processed without accrual",
+			response:   models.AccrualResponse{Status: models.AccrualStatusProcessed},
+			wantStatus: models.OrderStatusProcessed,
+		},
+		{
+			name:       "processed with negative accrual",
+			response:   models.AccrualResponse{Status: models.AccrualStatusProcessed, Accrual: accrualOf("-1")},
+			wantStatus: models.OrderStatusNew,
+			wantDelay:  true,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid",
+			response:   models.AccrualResponse{Status: models.AccrualStatusInvalid},
+			wantStatus: models.OrderStatusInvalid,
+		},
+		{
+			name:       "unknown status",
+			response:   models.AccrualResponse{Status: "CANCELLED"},
+			wantStatus: models.OrderStatusNew,
+			wantDelay:  true,
+			wantErr:    true,
+		},
+		{
+			name: "processed is not rolled back to processing",
+			before: []models.AccrualResponse{
+				{Status: models.AccrualStatusProcessed, Accrual: accrualOf("100")},
+			},
+			response:   models.AccrualResponse{Status: models.AccrualStatusProcessing},
+			wantStatus: models.OrderStatusProcessed,
+			wantAmount: 10000,
+		},
+		{
+			name: "invalid is not reprocessed",
+			before: []models.AccrualResponse{
+				{Status: models.AccrualStatusInvalid},
+			},
+			response:   models.AccrualResponse{Status: models.AccrualStatusProcessed, Accrual: accrualOf("100")},
+			wantStatus: models.OrderStatusInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc, store, fake, userID := newTestService(t)
+			job := models.AccrualJob{OrderNumber: testOrder, Attempts: 1}
+
+			for _, res := range tt.before {
+				fake.SetResponse(testOrder, res)
+				if _, err := svc.pollAccrual(ctx, job); err != nil {
+					t.Fatalf("preparing order: %v", err)
+				}
+			}
+
+			fake.SetResponse(testOrder, tt.response)
+			delay, err := svc.pollAccrual(ctx, job)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pollAccrual error = %v, want error %v", err, tt.wantErr)
+			}
+			if (delay > 0) != tt.wantDelay {
+				t.Errorf("pollAccrual delay = %v, want retry %v", delay, tt.wantDelay)
+			}
+
+			status, err := store.GetOrderStatus(ctx, testOrder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("order status = %s, want %s", status, tt.wantStatus)
+			}
+			balance, err := store.GetUserBalance(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance.Current != tt.wantAmount {
+				t.Errorf("balance = %v, want %v", balance.Current, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestApplyOrderStatusRejectsIllegalTransition(t *testing.T) {
+	ctx := context.Background()
+	svc, store, _, _ := newTestService(t)
+	if err := store.UpdateOrderStatus(ctx, testOrder, models.OrderStatusNew, models.OrderStatusProcessing); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := svc.applyOrderStatus(ctx, testOrder, models.OrderStatusNew, nil)
+	if !errors.Is(err, customerrors.ErrIllegalOrderTransition) {
+		t.Fatalf("applyOrderStatus error = %v, want ErrIllegalOrderTransition", err)
+	}
+}
+
+func TestCanTransitionOrder(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{models.OrderStatusNew, models.OrderStatusProcessing, true},
+		{models.OrderStatusNew, models.OrderStatusProcessed, true},
+		{models.OrderStatusProcessing, models.OrderStatusInvalid, true},
+		{models.OrderStatusProcessing, models.OrderStatusNew, false},
+		{models.OrderStatusProcessed, models.OrderStatusProcessing, false},
+		{models.OrderStatusInvalid, models.OrderStatusProcessed, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransitionOrder(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransitionOrder(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+const (
+	// bcrypt учитывает только первые 72 байта пароля
+	maxPasswordBytes = 72
+	passwordResetTTL = 30 * time.Minute
+)
+
+// Notifier доставляет пользователю токен сброса пароля.
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, notice models.PasswordResetNotice) error
+}
+
+// PasswordPolicy — требования к новым паролям; действующие пароли не перепроверяются.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// ParsePasswordPolicy разбирает строку вида "min=12,upper,lower,digit,symbol".
+func ParsePasswordPolicy(spec string) (PasswordPolicy, error) {
+	var p PasswordPolicy
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		switch {
+		case rule == "":
+		case strings.HasPrefix(rule, "min="):
+			n, err := strconv.Atoi(strings.TrimPrefix(rule, "min="))
+			if err != nil || n < 1 || n > maxPasswordBytes {
+				return p, fmt.Errorf("invalid password policy rule %q", rule)
+			}
+			p.MinLength = n
+		case rule == "upper":
+			p.RequireUpper = true
+		case rule == "lower":
+			p.RequireLower = true
+		case rule == "digit":
+			p.RequireDigit = true
+		case rule == "symbol":
+			p.RequireSymbol = true
+		default:
+			return p, fmt.Errorf("unknown password policy rule %q", rule)
+		}
+	}
+	return p, nil
+}
+
+func (p PasswordPolicy) Validate(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("%w: at least %d characters required", customerrors.ErrWeakPassword, p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: at most %d bytes allowed", customerrors.ErrWeakPassword, maxPasswordBytes)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	switch {
+	case p.RequireUpper && !upper:
+		return fmt.Errorf("%w: an uppercase letter required", customerrors.ErrWeakPassword)
+	case p.RequireLower && !lower:
+		return fmt.Errorf("%w: a lowercase letter required", customerrors.ErrWeakPassword)
+	case p.RequireDigit && !digit:
+		return fmt.Errorf("%w: a digit required", customerrors.ErrWeakPassword)
+	case p.RequireSymbol && !symbol:
+		return fmt.Errorf("%w: a symbol required", customerrors.ErrWeakPassword)
+	}
+	return nil
+}
+
+// dummyHash сравнивается с паролем для несуществующего логина, чтобы время ответа
+// не выдавало, зарегистрирован ли он.
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+func (s *Service) hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.auth.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Authenticate проверяет логин и пароль. Если хеш посчитан с меньшей стоимостью, чем настроенная,
+// пароль перехешируется.
+func (s *Service) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	user, err := s.repo.GetUserByLogin(ctx, login)
+	if errors.Is(err, customerrors.ErrUserNotFound) {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), s.auth.BcryptCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, customerrors.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, customerrors.ErrInvalidCredentials
+	}
+	// о блокировке сообщаем только после проверки пароля, чтобы не раскрывать её посторонним
+	if user.Blocked {
+		return nil, customerrors.ErrUserBlocked
+	}
+
+	if cost, err := bcrypt.Cost([]byte(user.PasswordHash)); err == nil && cost < s.auth.BcryptCost {
+		hash, err := s.hashPassword(password)
+		if err == nil {
+			err = s.repo.RehashPassword(ctx, user.ID, user.PasswordHash, hash)
+		}
+		if err != nil {
+			log.Printf("failed to rehash password of %s: %v", user.ID, err)
+		} else {
+			user.PasswordHash = hash
+		}
+	}
+	return user, nil
+}
+
+// ChangePassword меняет пароль и отзывает все сессии пользователя, кроме текущей.
+func (s *Service) ChangePassword(ctx context.Context, userID, sessionID, current, next string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	user, err := s.repo.GetUserByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)) != nil {
+		return customerrors.ErrInvalidCredentials
+	}
+	if err := s.auth.PasswordPolicy.Validate(next); err != nil {
+		return err
+	}
+
+	hash, err := s.hashPassword(next)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePasswordHash(ctx, uid, hash); err != nil {
+		return err
+	}
+	_, err = s.RevokeOtherSessions(ctx, userID, sessionID)
+	return err
+}
+
+// RequestPasswordReset отправляет токен сброса. Для неизвестного логина молча ничего не делает,
+// чтобы по ответу нельзя было проверить, существует ли пользователь.
+func (s *Service) RequestPasswordReset(ctx context.Context, login string) error {
+	user, err := s.repo.GetUserByLogin(ctx, login)
+	if errors.Is(err, customerrors.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	expiresAt, err := s.repo.CreatePasswordReset(ctx, user.ID, hash, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.auth.Notifier.SendPasswordReset(ctx, models.PasswordResetNotice{
+		UserID:    user.ID,
+		Login:     user.Login,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// ConfirmPasswordReset устанавливает новый пароль по токену сброса и отзывает все сессии.
+func (s *Service) ConfirmPasswordReset(ctx context.Context, token, password string) error {
+	if err := s.auth.PasswordPolicy.Validate(password); err != nil {
+		return err
+	}
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+	uid, err := s.repo.ConsumePasswordReset(ctx, auth.HashToken(token), hash)
+	if err != nil {
+		return err
+	}
+	_, err = s.RevokeOtherSessions(ctx, uid.String(), "")
+	return err
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+const (
+	expiryBatch              = 100
+	upcomingExpirationsLimit = 5
+)
+
+// PointsConfig — политика сгорания баллов.
+type PointsConfig struct {
+	// ExpiryMonths — через сколько месяцев после зачисления баллы сгорают; 0 — бессрочно.
+	// Срок фиксируется при зачислении, поэтому смена настройки не затрагивает прежние начисления.
+	ExpiryMonths int
+}
+
+// ExpirePoints списывает все просроченные партии баллов и возвращает число затронутых
+// пользователей и сгоревшую сумму.
+func (s *Service) ExpirePoints(ctx context.Context) (int, models.Points, error) {
+	var users int
+	var total models.Points
+	for {
+		batch, err := s.repo.GetUsersWithExpiredPoints(ctx, expiryBatch)
+		if err != nil {
+			return users, total, err
+		}
+		if len(batch) == 0 {
+			return users, total, nil
+		}
+		for _, userID := range batch {
+			expired, err := s.repo.ExpirePoints(ctx, userID)
+			if err != nil {
+				return users, total, err
+			}
+			users++
+			total += expired
+		}
+	}
+}
@@ -6,46 +6,68 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/accrual"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
 )
 
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+const (
+	accrualJobLease     = 30 * time.Second
+	pendingAccrualDelay = 3 * time.Second
+	maxRetryBackoff     = 5 * time.Minute
+	// запрос с Idempotency-Key, не завершённый за это время, считается брошенным
+	idempotencyLease = time.Minute
+)
+
+type AccrualClient interface {
+	GetOrderAccrual(ctx context.Context, orderNumber string) (*models.AccrualResponse, error)
+}
+
 type Service struct {
-	repo       repository.StoreRepositoryInterface
-	orderQueue chan string
-	accrualURL string
+	repo           repository.StoreRepositoryInterface
+	accrualClient  AccrualClient
+	accrualLimiter *RateLimiter
+	auth           AuthConfig
+	points         PointsConfig
+	sessions       *sessionCache
 }
 
-func NewService(repo repository.StoreRepositoryInterface, accrualURL string, orderQueue chan string) *Service {
+func NewService(repo repository.StoreRepositoryInterface, accrualClient AccrualClient, accrualLimiter *RateLimiter, authConfig AuthConfig, pointsConfig PointsConfig) *Service {
 	return &Service{
-		repo:       repo,
-		accrualURL: accrualURL,
-		orderQueue: orderQueue,
+		repo:           repo,
+		accrualClient:  accrualClient,
+		accrualLimiter: accrualLimiter,
+		auth:           authConfig,
+		points:         pointsConfig,
+		sessions:       newSessionCache(authConfig.SessionCacheTTL),
 	}
 }
 
-func (s *Service) CreateUser(login string, password string) (*models.User, error) {
-	user, err := s.repo.CreateUser(context.Background(), login, password)
+func (s *Service) CreateUser(ctx context.Context, login string, password string) (*models.User, error) {
+	if err := s.auth.PasswordPolicy.Validate(password); err != nil {
+		return nil, err
+	}
+	hash, err := s.hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
-
-}
-
-func (s *Service) GetUserByLogin(login string) (*models.User, error) {
-	user, err := s.repo.GetUserByLogin(context.Background(), login)
+	user, err := s.repo.CreateUser(ctx, login, hash)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
+
 }
 
 func (s *Service) SaveNewOrder(ctx context.Context, userID, orderNumber string) (int, error) {
@@ -63,98 +85,209 @@ func (s *Service) SaveNewOrder(ctx context.Context, userID, orderNumber string)
 		}
 		return 500, err
 	}
-	log.Printf("Status: %v", orderNumber)
-	s.EnqueueOrderForProcessing(orderNumber)
 	return 202, nil
 }
 
-func (s *Service) EnqueueOrderForProcessing(orderNumber string) {
-	select {
-	case s.orderQueue <- orderNumber:
-	default:
-		log.Printf("order queue full, dropping order: %s", orderNumber)
-	}
+func (s *Service) EnqueueOrderForProcessing(ctx context.Context, orderNumber string) (bool, error) {
+	return s.repo.EnqueueAccrualJob(ctx, orderNumber)
 }
 
-func (s *Service) ProcessAccrual(orderNumber string) {
-	url := fmt.Sprintf("%s/api/orders/%s", s.accrualURL, orderNumber)
-
-	for {
-		resp, err := http.Get(url)
+// EnqueuePendingOrders создаёт задачи для заказов в статусах NEW/PROCESSING, у которых их нет,
+// например для заказов, загруженных до появления очереди.
+func (s *Service) EnqueuePendingOrders(ctx context.Context) (int, error) {
+	orders, err := s.repo.GetPendingOrders(ctx)
+	if err != nil {
+		return 0, err
+	}
+	enqueued := 0
+	for _, orderNumber := range orders {
+		created, err := s.EnqueueOrderForProcessing(ctx, orderNumber)
 		if err != nil {
-			time.Sleep(time.Second)
-			continue
+			return enqueued, err
 		}
-
-		if resp.StatusCode == http.StatusTooManyRequests {
-			retry := time.Second * 5
-			if val := resp.Header.Get("Retry-After"); val != "" {
-				if sec, err := strconv.Atoi(val); err == nil {
-					retry = time.Duration(sec) * time.Second
-				}
-			}
-			resp.Body.Close()
-			time.Sleep(retry)
-			continue
+		if created {
+			enqueued++
 		}
+	}
+	return enqueued, nil
+}
 
-		if resp.StatusCode == http.StatusNoContent {
-			resp.Body.Close()
-			return
-		}
+// WaitAccrualSlot блокируется, пока общий лимитер не разрешит очередной запрос к системе начислений.
+func (s *Service) WaitAccrualSlot(ctx context.Context) error {
+	return s.accrualLimiter.Wait(ctx)
+}
 
-		var res models.AccrualResponse
-		err = json.NewDecoder(resp.Body).Decode(&res)
-		resp.Body.Close()
-		if err != nil {
-			time.Sleep(time.Second)
-			continue
-		}
+func (s *Service) LeaseAccrualJobs(ctx context.Context, limit int) ([]models.AccrualJob, error) {
+	return s.repo.LeaseAccrualJobs(ctx, limit, accrualJobLease)
+}
 
-		if res.Status == "REGISTERED" || res.Status == "PROCESSING" {
-			time.Sleep(3 * time.Second)
-			continue
+// ProcessAccrualJob делает одну попытку опроса системы начислений и либо закрывает задачу,
+// либо откладывает её до следующей попытки.
+func (s *Service) ProcessAccrualJob(ctx context.Context, job models.AccrualJob) {
+	delay, err := s.pollAccrual(ctx, job)
+	if err == nil && delay == 0 {
+		if err := s.repo.CompleteAccrualJob(ctx, job.OrderNumber); err != nil {
+			log.Printf("failed to complete accrual job %s: %v", job.OrderNumber, err)
 		}
+		return
+	}
 
-		if res.Status == "PROCESSED" {
-			log.Printf("accrual processed: %s +%.2f", res.Order, *res.Accrual)
-			if err := s.repo.UpdateOrderAccrual(context.Background(), res.Order, res.Status, *res.Accrual); err != nil {
-				log.Printf("failed to update accrual: %v", err)
+	lastErr := ""
+	if err != nil {
+		lastErr = err.Error()
+		log.Printf("accrual job %s attempt %d failed: %v", job.OrderNumber, job.Attempts, err)
+	}
+	if err := s.repo.RescheduleAccrualJob(ctx, job.OrderNumber, delay, lastErr); err != nil {
+		log.Printf("failed to reschedule accrual job %s: %v", job.OrderNumber, err)
+	}
+}
+
+// pollAccrual возвращает задержку до следующей попытки; нулевая задержка без ошибки означает,
+// что заказ обработан окончательно.
+func (s *Service) pollAccrual(ctx context.Context, job models.AccrualJob) (time.Duration, error) {
+	res, err := s.accrualClient.GetOrderAccrual(ctx, job.OrderNumber)
+	if errors.Is(err, accrual.ErrOrderNotRegistered) {
+		return retryBackoff(job.Attempts), nil
+	}
+	var retryErr *accrual.RetryAfterError
+	if errors.As(err, &retryErr) {
+		// притормаживаем всех воркеров, а не только текущий
+		s.accrualLimiter.PauseFor(retryErr.RetryAfter)
+		return retryErr.RetryAfter, nil
+	}
+	if err != nil {
+		return retryBackoff(job.Attempts), err
+	}
+
+	target, ok := orderStatusFromAccrual(res.Status)
+	if !ok {
+		return retryBackoff(job.Attempts), fmt.Errorf("unknown accrual status %q", res.Status)
+	}
+
+	final, err := s.applyOrderStatus(ctx, job.OrderNumber, target, res.Accrual)
+	if err != nil {
+		return retryBackoff(job.Attempts), err
+	}
+	if final {
+		return 0, nil
+	}
+	return pendingAccrualDelay, nil
+}
+
+// applyOrderStatus переводит заказ в target и сообщает, достигнут ли финальный статус.
+// Переход выполняется как compare-and-set от прочитанного статуса, поэтому параллельный
+// воркер не сможет вернуть заказ назад или начислить баллы повторно.
+func (s *Service) applyOrderStatus(ctx context.Context, orderNumber, target string, accrual *json.Number) (bool, error) {
+	current, err := s.repo.GetOrderStatus(ctx, orderNumber)
+	if err != nil {
+		return false, err
+	}
+	if IsFinalOrderStatus(current) {
+		return true, nil
+	}
+	if current == target {
+		return false, nil
+	}
+	if !CanTransitionOrder(current, target) {
+		return false, fmt.Errorf("%w: %s -> %s", customerrors.ErrIllegalOrderTransition, current, target)
+	}
+
+	if target == models.OrderStatusProcessed {
+		// отсутствие поля accrual означает, что начисления нет
+		var amount models.Points
+		if accrual != nil {
+			amount, err = models.RoundPoints(accrual.String())
+			if err != nil {
+				return false, err
+			}
+			if amount < 0 {
+				return false, fmt.Errorf("%w: negative accrual %s", models.ErrInvalidPoints, accrual)
 			}
-			return
 		}
+		err = s.repo.UpdateOrderAccrual(ctx, orderNumber, current, amount, s.points.ExpiryMonths)
+	} else {
+		err = s.repo.UpdateOrderStatus(ctx, orderNumber, current, target)
 	}
+	if err != nil {
+		return false, err
+	}
+	return IsFinalOrderStatus(target), nil
 }
 
-func (s *Service) GetUserOrders(ctx context.Context, userID string) ([]models.Order, error) {
+func retryBackoff(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// GetUserOrders возвращает страницу заказов и курсор следующей; без Limit — все заказы разом.
+func (s *Service) GetUserOrders(ctx context.Context, userID string, filter models.ListFilter) ([]models.Order, string, error) {
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	limit := filter.Limit
+	if limit > 0 {
+		limit = normalizeLimit(limit)
+		filter.Limit = limit + 1
 	}
-	orders, err := s.repo.GetOrdersByUser(ctx, uid)
+
+	orders, err := s.repo.GetOrdersByUser(ctx, uid, filter)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	if limit == 0 || len(orders) <= limit {
+		return orders, "", nil
 	}
-	return orders, nil
+	orders = orders[:limit]
+	last := orders[limit-1]
+	return orders, models.Cursor{At: last.UploadedAt, Key: last.Number}.Encode(), nil
 }
 
-func (s *Service) Withdraw(ctx context.Context, userID, order string, amount float64) error {
+// Withdraw списывает баллы; otp нужен только для сумм больше порога WithdrawOTPThreshold.
+func (s *Service) Withdraw(ctx context.Context, userID, order string, amount models.Points, otp string) error {
 	uid, err := uuid.Parse(userID)
 	if err != nil {
 		return err
 	}
+	if !amount.IsPositive() {
+		return customerrors.ErrInvalidAmount
+	}
 	if !IsValidLuhn(order) {
 		return customerrors.ErrInvalidOrderNumber
 	}
+	if err := s.requireWithdrawalOTP(ctx, uid, amount, otp); err != nil {
+		return err
+	}
 	return s.repo.Withdraw(ctx, uid, order, amount)
 }
 
-func (s *Service) GetWithdrawals(ctx context.Context, userID string) ([]models.Withdrawal, error) {
+func (s *Service) GetWithdrawals(ctx context.Context, userID string, filter models.ListFilter) ([]models.Withdrawal, string, error) {
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	limit := filter.Limit
+	if limit > 0 {
+		limit = normalizeLimit(limit)
+		filter.Limit = limit + 1
+	}
+
+	list, err := s.repo.GetWithdrawals(ctx, uid, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit == 0 || len(list) <= limit {
+		return list, "", nil
 	}
-	return s.repo.GetWithdrawals(ctx, uid)
+	list = list[:limit]
+	last := list[limit-1]
+	return list, models.Cursor{At: last.ProcessedAt, Key: strconv.Itoa(last.ID)}.Encode(), nil
 }
 
 func (s *Service) GetUserBalance(ctx context.Context, userID string) (*models.Balance, error) {
@@ -162,5 +295,73 @@ func (s *Service) GetUserBalance(ctx context.Context, userID string) (*models.Ba
 	if err != nil {
 		return nil, err
 	}
-	return s.repo.GetUserBalance(ctx, uid)
+	balance, err := s.repo.GetUserBalance(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	balance.UpcomingExpirations, err = s.repo.GetUpcomingExpirations(ctx, uid, upcomingExpirationsLimit)
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+func (s *Service) ReconcileBalances(ctx context.Context) (*models.ReconciliationReport, error) {
+	return s.repo.ReconcileBalances(ctx)
+}
+
+// GetTransactions возвращает страницу ленты операций и курсор следующей страницы,
+// пустой курсор означает, что страниц больше нет.
+func (s *Service) GetTransactions(ctx context.Context, userID string, filter models.TransactionFilter) ([]models.Transaction, string, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	limit := normalizeLimit(filter.Limit)
+	filter.Limit = limit + 1
+
+	list, err := s.repo.GetTransactions(ctx, uid, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(list) <= limit {
+		return list, "", nil
+	}
+	list = list[:limit]
+	last := list[limit-1]
+	return list, models.Cursor{At: last.OccurredAt, Key: last.Key}.Encode(), nil
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+func (s *Service) BeginIdempotentRequest(ctx context.Context, userID, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.BeginIdempotentRequest(ctx, uid, key, fingerprint, idempotencyLease)
+}
+
+func (s *Service) CompleteIdempotentRequest(ctx context.Context, userID, key string, statusCode int, body []byte) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	return s.repo.CompleteIdempotentRequest(ctx, uid, key, statusCode, body)
+}
+
+func (s *Service) ReleaseIdempotentRequest(ctx context.Context, userID, key string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	return s.repo.ReleaseIdempotentRequest(ctx, uid, key)
 }
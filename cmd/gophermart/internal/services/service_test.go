@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/accrual"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+)
+
+func TestPollAccrualNotRegistered(t *testing.T) {
+	svc, store, fake, _ := newTestService(t)
+	job := models.AccrualJob{OrderNumber: testOrder, Attempts: 3}
+
+	delay, err := svc.pollAccrual(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay != retryBackoff(job.Attempts) {
+		t.Errorf("delay = %v, want %v", delay, retryBackoff(job.Attempts))
+	}
+	if fake.Calls(testOrder) != 1 {
+		t.Errorf("got %d calls, want 1", fake.Calls(testOrder))
+	}
+	status, _ := store.GetOrderStatus(context.Background(), testOrder)
+	if status != models.OrderStatusNew {
+		t.Errorf("order status = %s, want NEW", status)
+	}
+}
+
+func TestPollAccrualRetryAfterPausesAllWorkers(t *testing.T) {
+	svc, _, fake, _ := newTestService(t)
+	fake.SetError(testOrder, &accrual.RetryAfterError{Err: accrual.ErrRateLimited, RetryAfter: time.Minute})
+
+	delay, err := svc.pollAccrual(context.Background(), models.AccrualJob{OrderNumber: testOrder, Attempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay != time.Minute {
+		t.Errorf("delay = %v, want 1m", delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := svc.WaitAccrualSlot(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitAccrualSlot = %v, want the limiter to stay paused", err)
+	}
+}
+
+func TestPollAccrualClientError(t *testing.T) {
+	svc, store, fake, _ := newTestService(t)
+	clientErr := errors.New("connection refused")
+	fake.SetError(testOrder, clientErr)
+	job := models.AccrualJob{OrderNumber: testOrder, Attempts: 2}
+
+	delay, err := svc.pollAccrual(context.Background(), job)
+	if !errors.Is(err, clientErr) {
+		t.Fatalf("error = %v, want %v", err, clientErr)
+	}
+	if delay != retryBackoff(job.Attempts) {
+		t.Errorf("delay = %v, want %v", delay, retryBackoff(job.Attempts))
+	}
+	status, _ := store.GetOrderStatus(context.Background(), testOrder)
+	if status != models.OrderStatusNew {
+		t.Errorf("order status = %s, want NEW", status)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{100, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sessionCache помнит недавно проверенные живые сессии, чтобы не ходить в базу на каждый запрос.
+// Отзыв через этот экземпляр виден сразу, через другие реплики — не позже чем через ttl.
+type sessionCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[uuid.UUID]time.Time
+	lastSweep time.Time
+}
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{ttl: ttl, entries: make(map[uuid.UUID]time.Time)}
+}
+
+func (c *sessionCache) alive(id uuid.UUID, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt, ok := c.entries[id]
+	if !ok {
+		return false
+	}
+	if !now.Before(expiresAt) {
+		delete(c.entries, id)
+		return false
+	}
+	return true
+}
+
+func (c *sessionCache) remember(id uuid.UUID, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// раз в ttl вычищаем просроченные записи, чтобы карта не росла бесконечно
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for k, exp := range c.entries {
+			if !now.Before(exp) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[id] = now.Add(c.ttl)
+}
+
+func (c *sessionCache) forget(ids ...uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		delete(c.entries, id)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/customerrors"
+)
+
+const (
+	totpIssuer             = "Gophermart"
+	recoveryCodesCount     = 10
+	loginChallengeTTL      = 5 * time.Minute
+	loginChallengeAttempts = 5
+)
+
+// EnrollTOTP выдаёт новый секрет; второй фактор включится после ConfirmTOTP.
+func (s *Service) EnrollTOTP(ctx context.Context, userID string) (*models.TOTPEnrollment, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveTOTPSecret(ctx, uid, secret); err != nil {
+		return nil, err
+	}
+	return &models.TOTPEnrollment{Secret: secret, URI: auth.TOTPURI(totpIssuer, user.Login, secret)}, nil
+}
+
+// ConfirmTOTP включает второй фактор по первому коду из приложения и возвращает коды
+// восстановления; в базе хранятся только их хеши, поэтому показать их повторно нельзя.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	totp, err := s.repo.GetTOTP(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if totp.Confirmed {
+		return nil, customerrors.ErrTOTPAlreadyEnabled
+	}
+	step, ok := auth.ValidateTOTP(totp.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, customerrors.ErrInvalidOTP
+	}
+
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		if codes[i], err = auth.NewRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = auth.HashToken(codes[i])
+	}
+	if err := s.repo.ConfirmTOTP(ctx, uid, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// BeginLogin вызывается после проверки пароля. Если второй фактор не включён, возвращает nil,
+// и токены можно выдавать сразу; иначе — токен входа, ожидающего кода.
+func (s *Service) BeginLogin(ctx context.Context, user *models.User, device string) (*models.LoginChallengeResponse, error) {
+	totp, err := s.repo.GetTOTP(ctx, user.ID)
+	if errors.Is(err, customerrors.ErrTOTPNotEnrolled) || (err == nil && !totp.Confirmed) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.CreateLoginChallenge(ctx, models.LoginChallenge{UserID: user.ID, Login: user.Login, Device: device}, hash, loginChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginChallengeResponse{ChallengeToken: token, ExpiresIn: int64(loginChallengeTTL.Seconds())}, nil
+}
+
+// LoginChallenge возвращает вход, ожидающий кода, чтобы проверить ограничения попыток по его логину.
+func (s *Service) LoginChallenge(ctx context.Context, challengeToken string) (*models.LoginChallenge, error) {
+	return s.repo.GetLoginChallenge(ctx, auth.HashToken(challengeToken), loginChallengeAttempts)
+}
+
+// CompleteLogin принимает код из приложения или код восстановления.
+func (s *Service) CompleteLogin(ctx context.Context, challengeToken, code string) (*models.LoginChallenge, error) {
+	hash := auth.HashToken(challengeToken)
+	ch, err := s.repo.GetLoginChallenge(ctx, hash, loginChallengeAttempts)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetUserByID(ctx, ch.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Blocked {
+		return nil, customerrors.ErrUserBlocked
+	}
+
+	ok, err := s.verifyTOTP(ctx, ch.UserID, code)
+	if err == nil && !ok {
+		ok, err = s.repo.UseRecoveryCode(ctx, ch.UserID, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.repo.FailLoginChallenge(ctx, hash); err != nil {
+			return nil, err
+		}
+		return nil, customerrors.ErrInvalidOTP
+	}
+
+	consumed, err := s.repo.ConsumeLoginChallenge(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, customerrors.ErrInvalidLoginChallenge
+	}
+	return ch, nil
+}
+
+// requireWithdrawalOTP проверяет код второго фактора для списаний больше порога.
+func (s *Service) requireWithdrawalOTP(ctx context.Context, userID uuid.UUID, amount models.Points, code string) error {
+	threshold := s.auth.WithdrawOTPThreshold
+	if threshold <= 0 || amount <= threshold {
+		return nil
+	}
+	if code == "" {
+		return customerrors.ErrTwoFactorRequired
+	}
+	ok, err := s.verifyTOTP(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return customerrors.ErrInvalidOTP
+	}
+	return nil
+}
+
+// verifyTOTP проверяет код из приложения; каждый код принимается только один раз.
+func (s *Service) verifyTOTP(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, customerrors.ErrTOTPNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !totp.Confirmed {
+		return false, nil
+	}
+	step, ok := auth.ValidateTOTP(totp.Secret, strings.TrimSpace(code), time.Now())
+	if !ok || step <= totp.LastStep {
+		return false, nil
+	}
+	return s.repo.UseTOTPStep(ctx, userID, step)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/config"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
+)
+
+// devSecretKey — ключ для локального запуска, в production с ним сервер не стартует.
+const devSecretKey = "verysecretkey"
+
+// loadKeyring берёт ключи подписи из файла, иначе из AUTH_KEYS, иначе из SECRET_KEY.
+func loadKeyring(cfg *config.Config) (*auth.Keyring, error) {
+	var keyring *auth.Keyring
+	var err error
+	switch {
+	case cfg.KeysFile != "":
+		keyring, err = auth.LoadKeyringFile(cfg.KeysFile)
+	case cfg.Keys != "":
+		keyring, err = auth.ParseKeyring(cfg.Keys)
+	case cfg.SecretKey != "":
+		keyring, err = auth.NewKeyring([]auth.Key{{ID: "default", Secret: cfg.SecretKey}})
+	case cfg.Production():
+		return nil, errors.New("no signing key configured: set AUTH_KEYS_FILE, AUTH_KEYS or SECRET_KEY")
+	default:
+		log.Println("no signing key configured, using insecure development key")
+		keyring, err = auth.NewKeyring([]auth.Key{{ID: "dev", Secret: devSecretKey}})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Production() && keyring.HasSecret(devSecretKey) {
+		return nil, errors.New("development secret key is not allowed in production")
+	}
+	return keyring, nil
+}
@@ -2,15 +2,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"golang.org/x/crypto/bcrypt"
+
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/config"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/accrual"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/async"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/handlers"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/notify"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/memory"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/postgresql"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/services"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/router"
@@ -19,24 +28,107 @@ import (
 func main() {
 	cfg := config.ParseFlags()
 
-	db, err := postgresql.InitDB(cfg.DBDSN)
+	if len(cfg.Args) > 0 && cfg.Args[0] == "migrate" {
+		if err := runMigrate(cfg.DBDSN, cfg.Args[1:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	keyring, err := loadKeyring(cfg)
+	if err != nil {
+		log.Fatalf("failed to load signing keys: %v", err)
+	}
+	log.Printf("signing tokens with key %q", keyring.ActiveKeyID())
+
+	passwordPolicy, err := services.ParsePasswordPolicy(cfg.PasswordPolicy)
+	if err != nil {
+		log.Fatalf("invalid password policy: %v", err)
+	}
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		log.Fatalf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	withdrawOTPThreshold, err := models.ParsePoints(cfg.WithdrawOTPThreshold)
+	if err != nil {
+		log.Fatalf("invalid withdraw 2FA threshold: %v", err)
+	}
+	if cfg.PointsExpiryMonths < 0 {
+		log.Fatalf("points expiry must not be negative, got %d months", cfg.PointsExpiryMonths)
+	}
+	notifier, err := notify.New(cfg.ResetNotifier)
 	if err != nil {
-		log.Fatalf("failed to initialize database: %v", err)
+		log.Fatalf("invalid password reset notifier: %v", err)
+	}
+
+	switch cfg.AuthLimiterStore {
+	case "memory":
+	case "postgres":
+		if cfg.DBDSN == "" {
+			log.Fatalf("auth limiter store %q requires a database DSN", cfg.AuthLimiterStore)
+		}
+	default:
+		log.Fatalf("unknown auth limiter store %q", cfg.AuthLimiterStore)
+	}
+
+	var repo repository.StoreRepositoryInterface
+	var attempts services.AttemptStore = memory.NewAttemptStore()
+	if cfg.DBDSN == "" {
+		log.Println("database DSN is not set, using in-memory storage")
+		repo = memory.NewMemoryStore()
+	} else {
+		db, err := postgresql.InitDB(cfg.DBDSN)
+		if err != nil {
+			log.Fatalf("failed to initialize database: %v", err)
+		}
+		defer postgresql.CloseDB(db)
+
+		repo = postgresql.NewDBStore(db)
+		if cfg.AuthLimiterStore == "postgres" {
+			attempts = postgresql.NewAttemptStore(db)
+		}
 	}
-	defer postgresql.CloseDB(db)
 
-	repo := postgresql.NewDBStore(db)
-	orderQueue := make(chan string, 100)
+	limiter := services.NewRateLimiter(cfg.AccrualRPS, cfg.Workers)
+	accrualOpts := accrual.DefaultOptions()
+	accrualOpts.RequestTimeout = cfg.AccrualTimeout
+	accrualClient := accrual.NewHTTPClient(cfg.Accrual, accrualOpts)
+	signer := auth.NewSigner(keyring, cfg.AccessTokenTTL)
+	service := services.NewService(repo, accrualClient, limiter, services.AuthConfig{
+		Signer:               signer,
+		RefreshTTL:           cfg.RefreshTokenTTL,
+		SessionCacheTTL:      cfg.SessionCacheTTL,
+		Attempts:             attempts,
+		Throttle:             services.DefaultThrottleConfig(),
+		PasswordPolicy:       passwordPolicy,
+		BcryptCost:           cfg.BcryptCost,
+		Notifier:             notifier,
+		WithdrawOTPThreshold: withdrawOTPThreshold,
+	}, services.PointsConfig{
+		ExpiryMonths: cfg.PointsExpiryMonths,
+	})
+	handler := handlers.NewHandler(service)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	service := services.NewService(repo, cfg.Accrual, orderQueue)
-	handler := handlers.NewHandler(service, cfg.SecretKey)
+	if err := service.EnsureAdmins(ctx, cfg.AdminLogins); err != nil {
+		log.Fatalf("failed to grant admin roles: %v", err)
+	}
 
 	// запуск воркера
-	async.StartOrderWorker(orderQueue, service)
+	async.StartOrderWorkers(ctx, service, cfg.Workers)
+	async.StartPendingOrdersSweeper(ctx, service, cfg.SweepInterval)
+	async.StartBalanceReconciler(ctx, service, cfg.ReconcileInterval)
+	async.StartPointsExpirer(ctx, service, cfg.ExpiryInterval)
+	async.StartAuditSealer(ctx, service, cfg.AuditSealInterval)
 
 	r := router.SetupRouter(router.Router{
-		Handler:   handler,
-		SecretKey: cfg.SecretKey,
+		Handler:          handler,
+		Signer:           signer,
+		Sessions:         service,
+		APIKeys:          service,
+		Roles:            service,
+		IdempotencyStore: service,
 	})
 
 	server := &http.Server{
@@ -56,6 +148,7 @@ func main() {
 
 	<-stop
 	log.Println("shutting down server...")
+	cancel()
 
 	if err := server.Close(); err != nil {
 		log.Printf("error shutting down server: %v", err)
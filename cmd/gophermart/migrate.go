@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/postgresql"
+)
+
+// runMigrate обрабатывает подкоманду `gophermart [flags] migrate up|down [N]|status`.
+func runMigrate(dsn string, args []string) error {
+	if dsn == "" {
+		return errors.New("database DSN is required")
+	}
+	if len(args) == 0 {
+		return errors.New("usage: gophermart migrate up|down [N]|status")
+	}
+
+	pool, err := postgresql.Connect(dsn)
+	if err != nil {
+		return err
+	}
+	defer postgresql.CloseDB(pool)
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := postgresql.MigrateUp(ctx, pool)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := postgresql.MigrateDown(ctx, pool, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		states, err := postgresql.MigrationStatus(ctx, pool)
+		if err != nil {
+			return err
+		}
+		for _, st := range states {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = "applied at " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
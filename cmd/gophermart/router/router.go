@@ -6,13 +6,19 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/handlers"
 	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/middlewares"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
 )
 
 type Router struct {
-	Handler   *handlers.Handler
-	SecretKey string
+	Handler          *handlers.Handler
+	Signer           *auth.Signer
+	Sessions         middlewares.SessionChecker
+	APIKeys          middlewares.APIKeyChecker
+	Roles            middlewares.RoleChecker
+	IdempotencyStore middlewares.IdempotencyStore
 }
 
 func SetupRouter(rt Router) http.Handler {
@@ -26,22 +32,65 @@ func SetupRouter(rt Router) http.Handler {
 	r := gin.New()
 
 	middlewares.InitLogger(sugar)
+	r.Use(middlewares.RequestIDMiddleware())
 	r.Use(middlewares.GinLoggingMiddleware())
 	r.Use(gin.Recovery())
 
 	r.POST("/api/user/register", rt.Handler.Register)
 	r.POST("/api/user/login", rt.Handler.Login)
+	r.POST("/api/user/login/2fa", rt.Handler.CompleteLogin)
+	r.POST("/api/user/token/refresh", rt.Handler.RefreshTokens)
+	r.POST("/api/user/password/reset", rt.Handler.RequestPasswordReset)
+	r.POST("/api/user/password/reset/confirm", rt.Handler.ConfirmPasswordReset)
 
-	auth := r.Group("/")
-	auth.Use(middlewares.AuthMiddleware(rt.SecretKey))
+	authorized := r.Group("/")
+	authorized.Use(middlewares.AuthMiddleware(rt.Signer, rt.Sessions, rt.APIKeys))
 
-	auth.POST("/api/user/orders", rt.Handler.UploadOrder)
-	auth.GET("/api/user/orders", rt.Handler.GetOrders)
+	// управление учётной записью доступно только по access-токену
+	account := authorized.Group("/")
+	account.Use(middlewares.SessionOnly())
 
-	auth.POST("/api/user/balance/withdraw", rt.Handler.Withdraw)
-	auth.GET("/api/user/withdrawals", rt.Handler.GetWithdrawals)
+	account.POST("/api/user/logout", rt.Handler.Logout)
+	account.POST("/api/user/password", rt.Handler.ChangePassword)
+	account.POST("/api/user/2fa/enroll", rt.Handler.EnrollTOTP)
+	account.POST("/api/user/2fa/confirm", rt.Handler.ConfirmTOTP)
+	account.GET("/api/user/sessions", rt.Handler.GetSessions)
+	account.DELETE("/api/user/sessions", rt.Handler.RevokeSessions)
+	account.DELETE("/api/user/sessions/:id", rt.Handler.RevokeSession)
+	account.POST("/api/user/api-keys", rt.Handler.CreateAPIKey)
+	account.GET("/api/user/api-keys", rt.Handler.GetAPIKeys)
+	account.DELETE("/api/user/api-keys/:id", rt.Handler.RevokeAPIKey)
 
-	auth.GET("/api/user/balance", rt.Handler.GetUserBalance)
+	authorized.POST("/api/user/orders", middlewares.RequireScope(models.ScopeOrdersWrite), rt.Handler.UploadOrder)
+	authorized.GET("/api/user/orders", middlewares.RequireScope(models.ScopeOrdersRead), rt.Handler.GetOrders)
+
+	authorized.POST("/api/user/balance/withdraw", middlewares.RequireScope(models.ScopeWithdraw),
+		middlewares.IdempotencyMiddleware(rt.IdempotencyStore), rt.Handler.Withdraw)
+	authorized.GET("/api/user/withdrawals", middlewares.RequireScope(models.ScopeBalanceRead), rt.Handler.GetWithdrawals)
+
+	authorized.GET("/api/user/balance", middlewares.RequireScope(models.ScopeBalanceRead), rt.Handler.GetUserBalance)
+	authorized.GET("/api/user/transactions", middlewares.RequireScope(models.ScopeBalanceRead), rt.Handler.GetTransactions)
+
+	admin := authorized.Group("/api/admin")
+	admin.Use(middlewares.SessionOnly(), middlewares.RequireRole(rt.Roles, models.RoleSupport, models.RoleAdmin))
+
+	admin.GET("/users", rt.Handler.AdminFindUser)
+	admin.GET("/users/:id", rt.Handler.AdminGetUser)
+	admin.GET("/users/:id/orders", rt.Handler.AdminGetOrders)
+	admin.GET("/users/:id/withdrawals", rt.Handler.AdminGetWithdrawals)
+	admin.GET("/users/:id/balance", rt.Handler.AdminGetBalance)
+	admin.POST("/orders/:number/accrual", rt.Handler.AdminRetriggerAccrual)
+	admin.GET("/adjustments", rt.Handler.AdminGetAdjustments)
+
+	adminOnly := middlewares.RequireRole(rt.Roles, models.RoleAdmin)
+	admin.POST("/users/:id/block", adminOnly, rt.Handler.AdminBlockUser)
+	admin.POST("/users/:id/unblock", adminOnly, rt.Handler.AdminUnblockUser)
+	admin.PUT("/users/:id/role", adminOnly, rt.Handler.AdminSetRole)
+	admin.POST("/users/:id/adjustments", adminOnly, rt.Handler.AdminProposeAdjustment)
+	admin.POST("/adjustments/:id/approve", adminOnly, rt.Handler.AdminApproveAdjustment)
+	admin.POST("/adjustments/:id/reject", adminOnly, rt.Handler.AdminRejectAdjustment)
+	admin.GET("/audit", adminOnly, rt.Handler.AdminGetAudit)
+	admin.GET("/audit/verify", adminOnly, rt.Handler.AdminVerifyAudit)
 
 	r.NoRoute(func(c *gin.Context) {
 		c.String(http.StatusBadRequest, "invalid request")
@@ -0,0 +1,312 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/accrual"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/async"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/auth"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/handlers"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/models"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/notify"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/memory"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/postgresql"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/repository/postgresql/pgtest"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/internal/services"
+	"github.com/rfruffer/go-musthave-diploma-tpl/cmd/gophermart/router"
+)
+
+const (
+	accruedOrder  = "12345678903"
+	accruedAmount = "729.98"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newAccrualStub имитирует систему начислений: accruedOrder обработан, остальные заказы не зарегистрированы.
+func newAccrualStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/orders/"+accruedOrder {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order":"` + accruedOrder + `","status":"PROCESSED","accrual":` + accruedAmount + `}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newServer собирает приложение так же, как main, и запускает воркеров опроса.
+func newServer(t *testing.T, repo repository.StoreRepositoryInterface) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	keyring, err := auth.NewKeyring([]auth.Key{{ID: "test", Secret: "integration-test-secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := services.ParsePasswordPolicy("min=8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier, err := notify.New("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accrualClient := accrual.NewHTTPClient(newAccrualStub(t).URL, accrual.DefaultOptions())
+	signer := auth.NewSigner(keyring, time.Minute)
+	service := services.NewService(repo, accrualClient, services.NewRateLimiter(0, 2), services.AuthConfig{
+		Signer:          signer,
+		RefreshTTL:      time.Hour,
+		SessionCacheTTL: time.Second,
+		Attempts:        memory.NewAttemptStore(),
+		Throttle:        services.DefaultThrottleConfig(),
+		PasswordPolicy:  policy,
+		BcryptCost:      bcrypt.MinCost,
+		Notifier:        notifier,
+	}, services.PointsConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	async.StartOrderWorkers(ctx, service, 2)
+
+	srv := httptest.NewServer(router.SetupRouter(router.Router{
+		Handler:          handlers.NewHandler(service),
+		Signer:           signer,
+		Sessions:         service,
+		APIKeys:          service,
+		Roles:            service,
+		IdempotencyStore: service,
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type client struct {
+	t     *testing.T
+	base  string
+	token string
+}
+
+// do не останавливает тест при сетевой ошибке, чтобы его можно было вызывать из горутин;
+// в этом случае статус ответа — 0.
+func (c *client) do(method, path, contentType, body string) (int, []byte) {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.base+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Errorf("%s %s: %v", method, path, err)
+		return 0, nil
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Errorf("%s %s: %v", method, path, err)
+		return 0, nil
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Errorf("%s %s: read body: %v", method, path, err)
+		return 0, nil
+	}
+	return resp.StatusCode, data
+}
+
+func (c *client) expect(want int, method, path, contentType, body string) []byte {
+	c.t.Helper()
+	code, resp := c.do(method, path, contentType, body)
+	if code != want {
+		c.t.Fatalf("%s %s: status %d, want %d, body %q", method, path, code, want, resp)
+	}
+	return resp
+}
+
+func (c *client) decode(data []byte, v interface{}) {
+	c.t.Helper()
+	if err := json.Unmarshal(data, v); err != nil {
+		c.t.Fatalf("decode %q: %v", data, err)
+	}
+}
+
+func (c *client) balance() models.Balance {
+	c.t.Helper()
+	var b models.Balance
+	c.decode(c.expect(http.StatusOK, http.MethodGet, "/api/user/balance", "", ""), &b)
+	return b
+}
+
+func (c *client) withdraw(order, sum string) int {
+	c.t.Helper()
+	code, _ := c.do(http.MethodPost, "/api/user/balance/withdraw", "application/json",
+		`{"order":"`+order+`","sum":`+sum+`}`)
+	return code
+}
+
+func points(t *testing.T, s string) models.Points {
+	t.Helper()
+	p, err := models.ParsePoints(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestEndToEnd(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		runScenario(t, memory.NewMemoryStore())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		runScenario(t, postgresql.NewDBStore(pgtest.NewDatabase(t)))
+	})
+}
+
+func runScenario(t *testing.T, repo repository.StoreRepositoryInterface) {
+	srv := newServer(t, repo)
+	c := &client{t: t, base: srv.URL}
+	credentials := `{"login":"buyer","password":"correct-horse"}`
+
+	c.expect(http.StatusOK, http.MethodPost, "/api/user/register", "application/json", credentials)
+	c.expect(http.StatusConflict, http.MethodPost, "/api/user/register", "application/json", credentials)
+	c.expect(http.StatusUnauthorized, http.MethodPost, "/api/user/login", "application/json",
+		`{"login":"buyer","password":"wrong-password"}`)
+	var tokens models.TokenPair
+	c.decode(c.expect(http.StatusOK, http.MethodPost, "/api/user/login", "application/json", credentials), &tokens)
+	c.token = tokens.AccessToken
+
+	c.expect(http.StatusAccepted, http.MethodPost, "/api/user/orders", "text/plain", accruedOrder)
+	c.expect(http.StatusOK, http.MethodPost, "/api/user/orders", "text/plain", accruedOrder)
+	c.expect(http.StatusUnprocessableEntity, http.MethodPost, "/api/user/orders", "text/plain", "12345678904")
+
+	// воркеры опрашивают очередь раз в секунду
+	var orders []models.Order
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		code, body := c.do(http.MethodGet, "/api/user/orders", "", "")
+		if code == http.StatusOK {
+			c.decode(body, &orders)
+			if len(orders) == 1 && orders[0].Status == models.OrderStatusProcessed {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("order was not processed in time: status %d, body %q", code, body)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if orders[0].Accrual == nil || *orders[0].Accrual != points(t, accruedAmount) {
+		t.Fatalf("accrual = %v, want %s", orders[0].Accrual, accruedAmount)
+	}
+
+	if b := c.balance(); b.Current != points(t, accruedAmount) || b.Withdrawn != 0 {
+		t.Fatalf("balance after accrual = %+v", b)
+	}
+
+	if code := c.withdraw("2377225624", "100"); code != http.StatusOK {
+		t.Fatalf("withdraw: status %d", code)
+	}
+	if code := c.withdraw("2377225624", "1"); code != http.StatusConflict {
+		t.Fatalf("withdraw with a used order: status %d, want 409", code)
+	}
+	if code := c.withdraw("2377225608", "1000"); code != http.StatusPaymentRequired {
+		t.Fatalf("withdraw above balance: status %d, want 402", code)
+	}
+	if b := c.balance(); b.Current != points(t, "629.98") || b.Withdrawn != points(t, "100") {
+		t.Fatalf("balance after withdrawal = %+v", b)
+	}
+
+	var withdrawals []models.Withdrawal
+	c.decode(c.expect(http.StatusOK, http.MethodGet, "/api/user/withdrawals", "", ""), &withdrawals)
+	if len(withdrawals) != 1 || withdrawals[0].Order != "2377225624" || withdrawals[0].Sum != points(t, "100") {
+		t.Fatalf("withdrawals = %+v", withdrawals)
+	}
+
+	// курсор ленты операций не подходит к выдаче списаний
+	feedCursor := models.Cursor{At: time.Now(), Key: "o:" + accruedOrder}.Encode()
+	c.expect(http.StatusBadRequest, http.MethodGet, "/api/user/withdrawals?cursor="+feedCursor, "", "")
+
+	// два одновременных списания, каждое из которых покрывается балансом, но не оба вместе
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i, order := range []string{"2377225616", "2377225632"} {
+		wg.Add(1)
+		go func(i int, order string) {
+			defer wg.Done()
+			codes[i] = c.withdraw(order, "600")
+		}(i, order)
+	}
+	wg.Wait()
+	if !(codes[0] == http.StatusOK && codes[1] == http.StatusPaymentRequired) &&
+		!(codes[0] == http.StatusPaymentRequired && codes[1] == http.StatusOK) {
+		t.Fatalf("concurrent withdrawals: statuses %v, want one 200 and one 402", codes)
+	}
+	if b := c.balance(); b.Current != points(t, "29.98") || b.Withdrawn != points(t, "700") {
+		t.Fatalf("balance after concurrent withdrawals = %+v", b)
+	}
+	c.decode(c.expect(http.StatusOK, http.MethodGet, "/api/user/withdrawals", "", ""), &withdrawals)
+	if len(withdrawals) != 2 {
+		t.Fatalf("withdrawals after race = %+v", withdrawals)
+	}
+}
+
+// login регистрирует пользователя и возвращает клиента с его access-токеном.
+func login(t *testing.T, base, name string) *client {
+	t.Helper()
+	c := &client{t: t, base: base}
+	credentials := `{"login":"` + name + `","password":"correct-horse"}`
+	c.expect(http.StatusOK, http.MethodPost, "/api/user/register", "application/json", credentials)
+	var tokens models.TokenPair
+	c.decode(c.expect(http.StatusOK, http.MethodPost, "/api/user/login", "application/json", credentials), &tokens)
+	c.token = tokens.AccessToken
+	return c
+}
+
+func TestAdjustmentsRequireAdmin(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryStore()
+	srv := newServer(t, repo)
+
+	customer := login(t, srv.URL, "customer")
+	support := login(t, srv.URL, "support")
+	admin := login(t, srv.URL, "admin")
+	for name, role := range map[string]string{"support": models.RoleSupport, "admin": models.RoleAdmin} {
+		user, err := repo.GetUserByLogin(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SetUserRole(ctx, user.ID, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var user models.UserInfo
+	support.decode(support.expect(http.StatusOK, http.MethodGet, "/api/admin/users?login=customer", "", ""), &user)
+
+	path := "/api/admin/users/" + user.ID.String() + "/adjustments"
+	body := `{"type":"credit","amount":100,"reason":"goodwill"}`
+	support.expect(http.StatusForbidden, http.MethodPost, path, "application/json", body)
+	customer.expect(http.StatusForbidden, http.MethodPost, path, "application/json", body)
+	admin.expect(http.StatusCreated, http.MethodPost, path, "application/json", body)
+}